@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"go.mau.fi/whatsmeow/store/sqlstore/upgrades"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// maxClockSkew adalah selisih jam maksimum sebelum handshake WhatsApp mulai
+// bermasalah.
+const maxClockSkew = 30 * time.Second
+
+type checkResult struct {
+	Name   string
+	OK     bool
+	Warn   bool
+	Detail string
+}
+
+type doctor struct {
+	results []checkResult
+}
+
+func (d *doctor) pass(name, detail string) {
+	d.results = append(d.results, checkResult{Name: name, OK: true, Detail: detail})
+}
+
+func (d *doctor) warn(name, detail string) {
+	d.results = append(d.results, checkResult{Name: name, OK: true, Warn: true, Detail: detail})
+}
+
+func (d *doctor) fail(name, detail string) {
+	d.results = append(d.results, checkResult{Name: name, Detail: detail})
+}
+
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	noConnect := fs.Bool("no-connect", false, "jangan akses jaringan sama sekali (lewati cek jam)")
+	connect := fs.Bool("connect", false, "tes login ke WhatsApp dengan device ini (gateway yang sedang jalan dengan session yang sama bisa terputus)")
+	timeout := fs.Duration("timeout", 20*time.Second, "batas waktu tes koneksi")
+	_ = fs.Parse(args)
+
+	d := &doctor{}
+
+	d.checkRuntime()
+	sqliteOK := d.checkSQLiteDriver()
+	d.checkDataDir()
+	sessionOK := sqliteOK && d.checkSession()
+	switch {
+	case *noConnect:
+		d.warn("Jam sistem", "dilewati (--no-connect)")
+		d.warn("Koneksi WhatsApp", "dilewati (--no-connect)")
+	case !*connect:
+		d.checkClock()
+		d.warn("Koneksi WhatsApp", "tidak dites, tambahkan --connect untuk tes login")
+	default:
+		d.checkClock()
+		if sessionOK {
+			d.checkConnection(context.Background(), *timeout)
+		}
+	}
+
+	failed := 0
+	for _, r := range d.results {
+		icon := "✅"
+		switch {
+		case !r.OK:
+			icon = "❌"
+			failed++
+		case r.Warn:
+			icon = "⚠️ "
+		}
+		fmt.Printf("%s %-22s %s\n", icon, r.Name, r.Detail)
+	}
+	if failed > 0 {
+		fmt.Printf("\n%d pemeriksaan gagal\n", failed)
+		return 1
+	}
+	fmt.Println("\nSemua pemeriksaan lolos")
+	return 0
+}
+
+func (d *doctor) checkRuntime() {
+	d.pass("Runtime", fmt.Sprintf("%s %s/%s", runtime.Version(), runtime.GOOS, runtime.GOARCH))
+}
+
+// checkSQLiteDriver memastikan mattn/go-sqlite3 benar-benar jalan. Binary yang
+// di-build dengan CGO_ENABLED=0 tetap punya driver "sqlite3", tapi semua
+// operasinya gagal.
+func (d *doctor) checkSQLiteDriver() bool {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		d.fail("Driver sqlite3", err.Error())
+		return false
+	}
+	defer db.Close()
+	var version string
+	if err := db.QueryRow("SELECT sqlite_version()").Scan(&version); err != nil {
+		d.fail("Driver sqlite3", "tidak bisa dipakai (build ulang dengan CGO_ENABLED=1): "+err.Error())
+		return false
+	}
+	d.pass("Driver sqlite3", "SQLite "+version+", CGO aktif")
+	return true
+}
+
+func (d *doctor) checkDataDir() {
+	dir, err := os.Getwd()
+	if err != nil {
+		d.fail("Direktori data", err.Error())
+		return
+	}
+	f, err := os.CreateTemp(dir, ".wa-cli-doctor-*")
+	if err != nil {
+		d.fail("Direktori data", dir+" tidak bisa ditulis: "+err.Error())
+		return
+	}
+	f.Close()
+	_ = os.Remove(f.Name())
+
+	st, err := os.Stat(filepath.Join(dir, sessionFile))
+	switch {
+	case os.IsNotExist(err):
+		d.warn("Direktori data", dir+" bisa ditulis, "+sessionFile+" belum ada")
+	case err != nil:
+		d.fail("Direktori data", err.Error())
+	default:
+		d.pass("Direktori data", fmt.Sprintf("%s bisa ditulis, %s %d byte (%s)", dir, sessionFile, st.Size(), st.Mode().Perm()))
+	}
+}
+
+// checkSession membuka session.db read-only: versi schema dan device dibaca
+// langsung dari tabel, tanpa sqlstore.New yang akan menjalankan upgrade.
+func (d *doctor) checkSession() bool {
+	if _, err := os.Stat(sessionFile); err != nil {
+		d.fail("Session", sessionFile+" tidak ditemukan, jalankan `wa-cli login`")
+		return false
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+sessionFile+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		d.fail("Schema session", err.Error())
+		return false
+	}
+	defer db.Close()
+	var version, compat int
+	err = db.QueryRow("SELECT version, compat FROM whatsmeow_version").Scan(&version, &compat)
+	latest := len(upgrades.Table)
+	switch {
+	case err != nil:
+		d.fail("Schema session", "tabel whatsmeow_version tidak terbaca: "+err.Error())
+		return false
+	case version > latest:
+		d.fail("Schema session", fmt.Sprintf("versi %d lebih baru dari yang didukung binary ini (%d)", version, latest))
+		return false
+	case version < latest:
+		d.warn("Schema session", fmt.Sprintf("versi %d, akan di-upgrade ke %d saat wa-cli login/send berikutnya", version, latest))
+	default:
+		d.pass("Schema session", fmt.Sprintf("versi %d (compat %d)", version, compat))
+	}
+
+	rows, err := db.Query("SELECT jid, push_name FROM whatsmeow_device")
+	if err != nil {
+		d.fail("Device", err.Error())
+		return false
+	}
+	defer rows.Close()
+	var jids, names []string
+	for rows.Next() {
+		var jid, name string
+		if err := rows.Scan(&jid, &name); err != nil {
+			d.fail("Device", err.Error())
+			return false
+		}
+		jids, names = append(jids, jid), append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		d.fail("Device", err.Error())
+		return false
+	}
+	if len(jids) == 0 {
+		d.fail("Device", "belum ada device yang ter-pair, jalankan `wa-cli login`")
+		return false
+	}
+	d.pass("Device", fmt.Sprintf("%d device tersimpan", len(jids)))
+	d.pass("JID ter-pair", jids[0])
+	if names[0] != "" {
+		d.pass("Push name terakhir", names[0])
+	} else {
+		d.warn("Push name terakhir", "kosong")
+	}
+	return true
+}
+
+// checkClock membandingkan jam lokal dengan header Date dari server WhatsApp
+// (presisi detik, cukup untuk mendeteksi jam yang melenceng jauh).
+func (d *doctor) checkClock() {
+	client := &http.Client{Timeout: 10 * time.Second}
+	before := time.Now()
+	resp, err := client.Head("https://web.whatsapp.com/")
+	if err != nil {
+		d.fail("Jam sistem", "tidak bisa menghubungi web.whatsapp.com: "+err.Error())
+		return
+	}
+	resp.Body.Close()
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		d.warn("Jam sistem", "server tidak mengirim header Date")
+		return
+	}
+	local := before.Add(time.Since(before) / 2)
+	skew := local.Sub(serverTime).Round(time.Second)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > maxClockSkew {
+		d.fail("Jam sistem", fmt.Sprintf("selisih %s dengan server, sinkronkan NTP", skew))
+		return
+	}
+	d.pass("Jam sistem", fmt.Sprintf("selisih %s dengan server", skew))
+}
+
+func (d *doctor) checkConnection(ctx context.Context, timeout time.Duration) {
+	client, err := openClient(ctx, waLog.Noop, waLog.Noop)
+	if err != nil {
+		d.fail("Koneksi WhatsApp", err.Error())
+		return
+	}
+	start := time.Now()
+	if err := client.Connect(); err != nil {
+		d.fail("Koneksi WhatsApp", err.Error())
+		return
+	}
+	defer client.Disconnect()
+	if !client.WaitForConnection(timeout) {
+		d.fail("Koneksi WhatsApp", "socket tersambung tapi login tidak selesai dalam "+timeout.String()+" (session dicabut dari HP?)")
+		return
+	}
+	d.pass("Koneksi WhatsApp", "login berhasil dalam "+time.Since(start).Round(time.Millisecond).String())
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"wa-common/chatexport"
+)
+
+// runExport merender transkrip satu chat dari arsip pesan gateway
+// (gateway.db, MESSAGE_ARCHIVE=1) ke JSON, CSV, HTML atau TXT ala WhatsApp.
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dbPath := fs.String("db", envOr("GATEWAY_DB", "gateway.db"), "path gateway.db yang berisi arsip pesan")
+	chat := fs.String("chat", "", "nomor atau JID chat yang diekspor")
+	format := fs.String("format", "txt", "json, csv, html atau txt")
+	from := fs.String("from", "", "mulai tanggal (YYYY-MM-DD atau RFC3339)")
+	to := fs.String("to", "", "sampai tanggal, inklusif")
+	zipOut := fs.Bool("zip", false, "bungkus hasil dalam zip")
+	media := fs.Bool("media", false, "ikut download media ke zip (butuh login, otomatis --zip)")
+	out := fs.String("o", "", "file output (default stdout)")
+	_ = fs.Parse(args)
+
+	if *chat == "" {
+		fmt.Fprintln(os.Stderr, "❌ --chat wajib diisi")
+		return 2
+	}
+	jid, err := parseRecipient(*chat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 2
+	}
+	opt := chatexport.Options{
+		Chat:     jid.String(),
+		Format:   strings.ToLower(*format),
+		Zip:      *zipOut || *media,
+		Media:    *media,
+		Location: time.Local,
+	}
+	if _, ok := chatexport.Formats[opt.Format]; !ok {
+		fmt.Fprintln(os.Stderr, "❌ --format harus json, csv, html atau txt")
+		return 2
+	}
+	if opt.From, err = chatexport.ParseTime(*from, false, opt.Location); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 2
+	}
+	if opt.To, err = chatexport.ParseTime(*to, true, opt.Location); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 2
+	}
+	if opt.Zip && *out == "" && isTerminal(os.Stdout) {
+		fmt.Fprintln(os.Stderr, "❌ output zip tidak bisa ditulis ke terminal, pakai -o file.zip")
+		return 2
+	}
+
+	if _, err := os.Stat(*dbPath); err != nil {
+		fmt.Fprintln(os.Stderr, "❌ arsip tidak ditemukan:", err)
+		return 1
+	}
+	db, err := sql.Open("sqlite3", "file:"+*dbPath+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ gagal buka arsip:", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	var download func(*waProto.Message) ([]byte, error)
+	if opt.Media {
+		client, err := openClient(ctx, waLog.Noop, waLog.Noop)
+		if err == nil {
+			err = connectLoggedIn(client)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "❌ media butuh session aktif:", err)
+			return 1
+		}
+		defer client.Disconnect()
+		opt.SelfName = client.Store.PushName
+		download = func(m *waProto.Message) ([]byte, error) {
+			ctx, cancel := context.WithTimeout(ctx, time.Minute)
+			defer cancel()
+			return client.DownloadAny(ctx, m)
+		}
+	}
+
+	exported, err := chatexport.Load(db, opt)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "❌", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := chatexport.Write(w, exported, opt, download); err != nil {
+		fmt.Fprintln(os.Stderr, "❌ gagal export:", err)
+		return 1
+	}
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "✅ %d pesan diekspor ke %s\n", len(exported.Messages), *out)
+	}
+	return 0
+}
+
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// cliEvent adalah bentuk event yang sudah dinormalisasi, satu baris JSON per event
+// di `wa-cli listen --json` supaya gampang diproses jq / grep.
+type cliEvent struct {
+	Type     string      `json:"type"`
+	Time     time.Time   `json:"time"`
+	ID       string      `json:"id,omitempty"`
+	Chat     string      `json:"chat,omitempty"`
+	Sender   string      `json:"sender,omitempty"`
+	Phone    string      `json:"phone,omitempty"` // nomor pengirim, juga untuk pengirim @lid kalau diketahui
+	PushName string      `json:"push_name,omitempty"`
+	IsGroup  bool        `json:"is_group"`
+	FromMe   bool        `json:"from_me"`
+	MsgType  string      `json:"msg_type,omitempty"`
+	Text     string      `json:"text,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+
+	phoneChat string // chat pribadi @lid dalam bentuk JID nomor, untuk filter --chat
+}
+
+// eventTypes adalah daftar nilai yang valid untuk --types.
+var eventTypes = []string{"message", "receipt", "presence", "chat_presence", "group", "picture", "call", "connection"}
+
+type listenFilter struct {
+	chats      map[string]bool
+	senders    map[string]bool
+	types      map[string]bool
+	groupsOnly bool
+	fromMe     bool
+}
+
+func (f listenFilter) match(e cliEvent) bool {
+	if len(f.types) > 0 && !f.types[e.Type] {
+		return false
+	}
+	if e.Type == "connection" {
+		return true
+	}
+	if e.FromMe && !f.fromMe {
+		return false
+	}
+	if f.groupsOnly && !e.IsGroup {
+		return false
+	}
+	if len(f.chats) > 0 && !f.chats[e.Chat] && !f.chats[e.phoneChat] {
+		return false
+	}
+	if len(f.senders) > 0 && !f.senders[e.Sender] &&
+		(e.Phone == "" || !f.senders[types.NewJID(e.Phone, types.DefaultUserServer).String()]) {
+		return false
+	}
+	return true
+}
+
+func runListen(args []string) int {
+	fs := flag.NewFlagSet("listen", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "cetak satu event JSON per baris")
+	chats := fs.String("chat", "", "filter chat (nomor/JID, pisahkan dengan koma)")
+	senders := fs.String("sender", "", "filter pengirim (nomor/JID, pisahkan dengan koma)")
+	typeList := fs.String("types", "message", "jenis event, pisahkan koma: "+strings.Join(eventTypes, ",")+" atau all")
+	groupsOnly := fs.Bool("groups-only", false, "hanya event dari grup")
+	fromMe := fs.Bool("from-me", false, "ikut tampilkan pesan yang kita kirim sendiri")
+	debug := fs.Bool("debug", false, "tampilkan log whatsmeow")
+	_ = fs.Parse(args)
+
+	filter := listenFilter{groupsOnly: *groupsOnly, fromMe: *fromMe}
+	var err error
+	if filter.chats, err = jidSet(*chats); err != nil {
+		fmt.Fprintln(os.Stderr, "❌ --chat:", err)
+		return 2
+	}
+	if filter.senders, err = jidSet(*senders); err != nil {
+		fmt.Fprintln(os.Stderr, "❌ --sender:", err)
+		return 2
+	}
+	if *typeList != "all" {
+		filter.types = map[string]bool{}
+		for _, t := range splitList(*typeList) {
+			if !contains(eventTypes, t) {
+				fmt.Fprintln(os.Stderr, "❌ jenis event tidak dikenal:", t)
+				return 2
+			}
+			filter.types[t] = true
+		}
+	}
+
+	ctx := context.Background()
+	logger := waLog.Noop
+	if *debug {
+		logger = waLog.Stdout("Client", "DEBUG", true)
+	}
+	client, err := openClient(ctx, waLog.Noop, logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+
+	var outMu sync.Mutex
+	enc := json.NewEncoder(os.Stdout)
+	client.AddEventHandler(func(raw interface{}) {
+		e, ok := normalizeEvent(raw)
+		if !ok {
+			return
+		}
+		switch v := raw.(type) {
+		case *events.Message:
+			resolvePhone(client, &e, v.Info.MessageSource)
+		case *events.Receipt:
+			resolvePhone(client, &e, v.MessageSource)
+		}
+		if !filter.match(e) {
+			return
+		}
+		outMu.Lock()
+		defer outMu.Unlock()
+		if *asJSON {
+			_ = enc.Encode(e)
+			return
+		}
+		printEvent(e)
+	})
+
+	if err := connectLoggedIn(client); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+
+	// tunggu CTRL+C
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	<-c
+	client.Disconnect()
+	return 0
+}
+
+// normalizeEvent memetakan event whatsmeow ke cliEvent. Event yang tidak
+// dikenal dikembalikan dengan ok=false.
+func normalizeEvent(raw interface{}) (e cliEvent, ok bool) {
+	switch v := raw.(type) {
+	case *events.Message:
+		return cliEvent{
+			Type:     "message",
+			Time:     v.Info.Timestamp,
+			ID:       v.Info.ID,
+			Chat:     v.Info.Chat.String(),
+			Sender:   v.Info.Sender.ToNonAD().String(),
+			PushName: v.Info.PushName,
+			IsGroup:  v.Info.IsGroup,
+			FromMe:   v.Info.IsFromMe,
+			MsgType:  messageType(v.Message),
+			Text:     messageText(v.Message),
+		}, true
+	case *events.Receipt:
+		return cliEvent{
+			Type:    "receipt",
+			Time:    v.Timestamp,
+			ID:      strings.Join(v.MessageIDs, ","),
+			Chat:    v.Chat.String(),
+			Sender:  v.Sender.ToNonAD().String(),
+			IsGroup: v.IsGroup,
+			FromMe:  v.IsFromMe,
+			Data:    map[string]interface{}{"type": receiptType(v.Type), "message_ids": v.MessageIDs},
+		}, true
+	case *events.Presence:
+		return cliEvent{
+			Type:   "presence",
+			Time:   time.Now(),
+			Chat:   v.From.String(),
+			Sender: v.From.String(),
+			Data:   map[string]interface{}{"unavailable": v.Unavailable, "last_seen": v.LastSeen},
+		}, true
+	case *events.ChatPresence:
+		return cliEvent{
+			Type:    "chat_presence",
+			Time:    time.Now(),
+			Chat:    v.Chat.String(),
+			Sender:  v.Sender.ToNonAD().String(),
+			IsGroup: v.IsGroup,
+			FromMe:  v.IsFromMe,
+			Data:    map[string]interface{}{"state": v.State, "media": v.Media},
+		}, true
+	case *events.GroupInfo:
+		return cliEvent{
+			Type:    "group",
+			Time:    v.Timestamp,
+			Chat:    v.JID.String(),
+			Sender:  jidString(v.Sender),
+			IsGroup: true,
+			Data:    v,
+		}, true
+	case *events.JoinedGroup:
+		return cliEvent{
+			Type:    "group",
+			Time:    time.Now(),
+			Chat:    v.JID.String(),
+			IsGroup: true,
+			Data:    map[string]interface{}{"joined": true, "reason": v.Reason, "name": v.Name},
+		}, true
+	case *events.Picture:
+		return cliEvent{
+			Type:    "picture",
+			Time:    v.Timestamp,
+			Chat:    v.JID.String(),
+			Sender:  v.Author.String(),
+			IsGroup: v.JID.Server == types.GroupServer,
+			Data:    map[string]interface{}{"picture_id": v.PictureID, "removed": v.Remove},
+		}, true
+	case *events.CallOffer:
+		return cliEvent{
+			Type:   "call",
+			Time:   v.Timestamp,
+			ID:     v.CallID,
+			Chat:   v.From.ToNonAD().String(),
+			Sender: v.CallCreator.ToNonAD().String(),
+			Data:   map[string]interface{}{"state": "offer"},
+		}, true
+	case *events.Connected:
+		return cliEvent{Type: "connection", Time: time.Now(), Data: map[string]string{"state": "connected"}}, true
+	case *events.Disconnected:
+		return cliEvent{Type: "connection", Time: time.Now(), Data: map[string]string{"state": "disconnected"}}, true
+	case *events.LoggedOut:
+		return cliEvent{Type: "connection", Time: time.Now(), Data: map[string]string{"state": "logged_out"}}, true
+	}
+	return cliEvent{}, false
+}
+
+// resolvePhone mengisi nomor pengirim dan chat untuk pesan yang dialamatkan
+// lewat @lid, supaya --sender/--chat dengan nomor tetap cocok.
+func resolvePhone(client *whatsmeow.Client, e *cliEvent, src types.MessageSource) {
+	if pn, ok := phoneJID(client, src.Sender, src.SenderAlt); ok {
+		e.Phone = pn.User
+	}
+	alt := src.SenderAlt
+	if src.IsFromMe {
+		alt = src.RecipientAlt
+	}
+	if pn, ok := phoneJID(client, src.Chat, alt); ok && pn != src.Chat {
+		e.phoneChat = pn.String()
+	}
+}
+
+// phoneJID menerjemahkan JID @lid ke JID nomor lewat alt atau pemetaan LID
+// di session; ok false kalau belum bisa.
+func phoneJID(client *whatsmeow.Client, jid, alt types.JID) (types.JID, bool) {
+	jid = jid.ToNonAD()
+	switch {
+	case jid.Server == types.DefaultUserServer:
+		return jid, true
+	case jid.Server != types.HiddenUserServer:
+		return jid, false
+	case alt.Server == types.DefaultUserServer:
+		return alt.ToNonAD(), true
+	}
+	if pn, err := client.Store.LIDs.GetPNForLID(context.Background(), jid); err == nil && !pn.IsEmpty() {
+		return pn.ToNonAD(), true
+	}
+	return jid, false
+}
+
+func printEvent(e cliEvent) {
+	ts := e.Time.Local().Format("15:04:05")
+	switch e.Type {
+	case "message":
+		who := e.PushName
+		if who == "" {
+			who = e.Sender
+		}
+		if e.IsGroup {
+			who = e.Chat + " / " + who
+		}
+		text := e.Text
+		if text == "" {
+			text = "<" + e.MsgType + ">"
+		}
+		fmt.Printf("%s 💬 %s: %s\n", ts, who, text)
+	default:
+		data, _ := json.Marshal(e.Data)
+		fmt.Printf("%s ℹ️  %s %s %s\n", ts, e.Type, e.Chat, data)
+	}
+}
+
+// messageText mengambil teks yang terbaca dari pesan: isi teks biasa,
+// extended text (reply/link) atau caption media.
+func messageText(m *waProto.Message) string {
+	switch {
+	case m == nil:
+		return ""
+	case m.GetConversation() != "":
+		return m.GetConversation()
+	case m.GetExtendedTextMessage() != nil:
+		return m.GetExtendedTextMessage().GetText()
+	case m.GetImageMessage() != nil:
+		return m.GetImageMessage().GetCaption()
+	case m.GetVideoMessage() != nil:
+		return m.GetVideoMessage().GetCaption()
+	case m.GetDocumentMessage() != nil:
+		return m.GetDocumentMessage().GetCaption()
+	}
+	return ""
+}
+
+func messageType(m *waProto.Message) string {
+	switch {
+	case m == nil:
+		return "unknown"
+	case m.Conversation != nil, m.ExtendedTextMessage != nil:
+		return "text"
+	case m.ImageMessage != nil:
+		return "image"
+	case m.VideoMessage != nil:
+		return "video"
+	case m.AudioMessage != nil:
+		return "audio"
+	case m.DocumentMessage != nil:
+		return "document"
+	case m.StickerMessage != nil:
+		return "sticker"
+	case m.LocationMessage != nil, m.LiveLocationMessage != nil:
+		return "location"
+	case m.ContactMessage != nil, m.ContactsArrayMessage != nil:
+		return "contact"
+	case m.ReactionMessage != nil:
+		return "reaction"
+	case m.PollCreationMessage != nil, m.PollCreationMessageV2 != nil, m.PollCreationMessageV3 != nil:
+		return "poll"
+	case m.PollUpdateMessage != nil:
+		return "poll_vote"
+	case m.ProtocolMessage != nil:
+		return "protocol"
+	}
+	return "unknown"
+}
+
+func receiptType(t types.ReceiptType) string {
+	if t == types.ReceiptTypeDelivered {
+		return "delivered"
+	}
+	return string(t)
+}
+
+func jidString(j *types.JID) string {
+	if j == nil {
+		return ""
+	}
+	return j.ToNonAD().String()
+}
+
+// jidSet mengubah daftar nomor/JID dipisah koma menjadi set JID string.
+func jidSet(list string) (map[string]bool, error) {
+	items := splitList(list)
+	if len(items) == 0 {
+		return nil, nil
+	}
+	set := make(map[string]bool, len(items))
+	for _, s := range items {
+		jid, err := parseRecipient(s)
+		if err != nil {
+			return nil, err
+		}
+		set[jid.String()] = true
+	}
+	return set, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3" // PENTING: registrasi driver
 	"github.com/mdp/qrterminal/v3"  // tambahan agar QR tampil di terminal
@@ -15,50 +17,133 @@ import (
 	waLog "go.mau.fi/whatsmeow/util/log"
 )
 
+// sessionFile adalah database session whatsmeow, relatif ke direktori kerja.
+const sessionFile = "session.db"
+
+const usage = `Pemakaian:
+  wa-cli [login]                         login (scan QR) lalu tampilkan pesan masuk
+  wa-cli send --to 628xxx "teks"         kirim pesan teks
+  wa-cli send --to 628xxx --file a.pdf   kirim file (gambar/video/audio/dokumen)
+  ... | wa-cli send [--to 628xxx]        kirim tiap baris stdin (teks atau JSON)
+  wa-cli listen [--json] [filter]        tampilkan event masuk (lihat wa-cli listen -h)
+  wa-cli tui                             chat interaktif layar penuh
+  wa-cli doctor [--connect|--no-connect] cek kesehatan session dan environment
+  wa-cli export --chat 628xxx [--format txt|json|csv|html] [--zip] [-o file]
+                                         ekspor transkrip dari arsip gateway.db
+
+Nomor boleh ditulis 0898..., +62 898-1389-448 atau 628...; nomor lokal memakai
+kode negara dari env DEFAULT_COUNTRY_CODE (default 62).
+`
+
 func eventHandler(evt interface{}) {
 	switch v := evt.(type) {
 	case *events.Message:
-		fmt.Println("💬 Pesan masuk:", v.Message.GetConversation())
+		fmt.Println("💬 Pesan masuk:", messageText(v.Message))
 	}
 }
 
 func main() {
-	dbLog := waLog.Stdout("Database", "DEBUG", true)
-	ctx := context.Background()
+	cmd := ""
+	if len(os.Args) > 1 {
+		cmd = os.Args[1]
+	}
+	switch cmd {
+	case "", "login":
+		runLogin()
+	case "send":
+		os.Exit(runSend(os.Args[2:]))
+	case "listen":
+		os.Exit(runListen(os.Args[2:]))
+	case "tui":
+		os.Exit(runTUI(os.Args[2:]))
+	case "doctor":
+		os.Exit(runDoctor(os.Args[2:]))
+	case "export":
+		os.Exit(runExport(os.Args[2:]))
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintln(os.Stderr, "❌ Perintah tidak dikenal:", cmd)
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
 
+// openClient membuka session.db dan membuat client whatsmeow di atasnya.
+// Subcommand memakai log Noop supaya stdout tetap bersih untuk script.
+func openClient(ctx context.Context, dbLog, clientLog waLog.Logger) (*whatsmeow.Client, error) {
 	// pastikan driver "sqlite3" (mattn/go-sqlite3) digunakan
-	container, err := sqlstore.New(ctx, "sqlite3", "file:session.db?_foreign_keys=on", dbLog)
+	container, err := sqlstore.New(ctx, "sqlite3", "file:"+sessionFile+"?_foreign_keys=on", dbLog)
 	if err != nil {
-		panic("❌ Gagal buka DB: " + err.Error())
+		return nil, fmt.Errorf("gagal buka DB: %w", err)
 	}
 
 	deviceStore, err := container.GetFirstDevice(ctx)
 	if err != nil {
-		panic("❌ Gagal ambil device: " + err.Error())
+		return nil, fmt.Errorf("gagal ambil device: %w", err)
 	}
 
-	clientLog := waLog.Stdout("Client", "DEBUG", true)
-	client := whatsmeow.NewClient(deviceStore, clientLog)
-	client.AddEventHandler(eventHandler)
+	return whatsmeow.NewClient(deviceStore, clientLog), nil
+}
 
+// connectLoggedIn menyambungkan session yang sudah login dan menunggu
+// sampai client siap kirim/terima pesan.
+func connectLoggedIn(client *whatsmeow.Client) error {
 	if client.Store.ID == nil {
-		qrChan, _ := client.GetQRChannel(ctx)
-		if err := client.Connect(); err != nil {
-			panic("❌ Gagal connect: " + err.Error())
+		return errors.New("belum login, jalankan `wa-cli login` untuk scan QR")
+	}
+	if err := client.Connect(); err != nil {
+		return fmt.Errorf("gagal connect: %w", err)
+	}
+	if !client.WaitForConnection(30 * time.Second) {
+		client.Disconnect()
+		return errors.New("timeout menunggu koneksi WhatsApp")
+	}
+	return nil
+}
+
+// pairWithQR menampilkan QR di terminal dan menunggu sampai HP selesai scan.
+func pairWithQR(ctx context.Context, client *whatsmeow.Client) error {
+	qrChan, _ := client.GetQRChannel(ctx)
+	if err := client.Connect(); err != nil {
+		return fmt.Errorf("gagal connect: %w", err)
+	}
+
+	success := false
+	for evt := range qrChan {
+		switch evt.Event {
+		case "code":
+			qrterminal.GenerateHalfBlock(evt.Code, qrterminal.L, os.Stdout)
+			fmt.Println("📱 Scan QR dengan WhatsApp Anda")
+		case "success":
+			success = true
+			fmt.Println("✅ Login berhasil!")
+		case "timeout":
+			fmt.Println("⏱️  QR timeout")
+		default:
+			fmt.Println("ℹ️  QR event:", evt.Event)
 		}
+	}
+	if !success {
+		return errors.New("login QR tidak selesai")
+	}
+	return nil
+}
 
-		for evt := range qrChan {
-			switch evt.Event {
-			case "code":
-				qrterminal.GenerateHalfBlock(evt.Code, qrterminal.L, os.Stdout)
-				fmt.Println("📱 Scan QR dengan WhatsApp Anda")
-			case "success":
-				fmt.Println("✅ Login berhasil!")
-			case "timeout":
-				fmt.Println("⏱️  QR timeout")
-			default:
-				fmt.Println("ℹ️  QR event:", evt.Event)
-			}
+func runLogin() {
+	dbLog := waLog.Stdout("Database", "DEBUG", true)
+	ctx := context.Background()
+
+	clientLog := waLog.Stdout("Client", "DEBUG", true)
+	client, err := openClient(ctx, dbLog, clientLog)
+	if err != nil {
+		panic("❌ " + err.Error())
+	}
+	client.AddEventHandler(eventHandler)
+
+	if client.Store.ID == nil {
+		if err := pairWithQR(ctx, client); err != nil {
+			fmt.Println("❌", err)
 		}
 	} else {
 		fmt.Println("✅ Sudah login sebagai", client.Store.ID.User)
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"google.golang.org/protobuf/proto"
+	"wa-common/phone"
+)
+
+// sendLine adalah format satu baris JSON di mode pipe, mis.
+// {"to":"628xxx","message":"halo"} atau {"to":"628xxx","file":"a.pdf","caption":"invoice"}.
+type sendLine struct {
+	To      string `json:"to"`
+	Message string `json:"message"`
+	File    string `json:"file"`
+	Caption string `json:"caption"`
+}
+
+func runSend(args []string) int {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	to := fs.String("to", "", "nomor atau JID tujuan (default untuk mode pipe)")
+	file := fs.String("file", "", "path file yang dikirim")
+	caption := fs.String("caption", "", "caption untuk file")
+	stdin := fs.Bool("stdin", false, "paksa baca pesan dari stdin, satu per baris")
+	debug := fs.Bool("debug", false, "tampilkan log whatsmeow")
+	_ = fs.Parse(args)
+	text := strings.Join(fs.Args(), " ")
+
+	pipe := *stdin || (text == "" && *file == "" && !isTerminal(os.Stdin))
+	if !pipe && *to == "" {
+		fmt.Fprintln(os.Stderr, "❌ --to wajib diisi")
+		return 2
+	}
+	if !pipe && text == "" && *file == "" {
+		fmt.Fprintln(os.Stderr, "❌ isi pesan atau --file wajib diisi")
+		return 2
+	}
+
+	ctx := context.Background()
+	logger := waLog.Noop
+	if *debug {
+		logger = waLog.Stdout("Client", "DEBUG", true)
+	}
+	client, err := openClient(ctx, waLog.Noop, logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	if err := connectLoggedIn(client); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	defer client.Disconnect()
+
+	if !pipe {
+		if err := sendOne(ctx, client, sendLine{To: *to, Message: text, File: *file, Caption: *caption}); err != nil {
+			fmt.Fprintln(os.Stderr, "❌", err)
+			return 1
+		}
+		return 0
+	}
+
+	sent, failed := 0, 0
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		item := sendLine{To: *to, Message: line}
+		if strings.HasPrefix(line, "{") {
+			item = sendLine{}
+			if err := json.Unmarshal([]byte(line), &item); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ baris %d: JSON tidak valid: %v\n", n, err)
+				failed++
+				continue
+			}
+			if item.To == "" {
+				item.To = *to
+			}
+		}
+		if err := sendOne(ctx, client, item); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ baris %d: %v\n", n, err)
+			failed++
+			continue
+		}
+		sent++
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "❌ gagal baca stdin:", err)
+		return 1
+	}
+	if failed > 0 {
+		return 1
+	}
+	if sent == 0 {
+		// mis. dijalankan dari cron atau dengan </dev/null
+		fmt.Fprintln(os.Stderr, "❌ tidak ada input: stdin kosong, tidak ada pesan yang dikirim")
+		return 1
+	}
+	return 0
+}
+
+func sendOne(ctx context.Context, client *whatsmeow.Client, item sendLine) error {
+	if item.To == "" {
+		return errors.New("tujuan kosong, isi --to atau field \"to\"")
+	}
+	jid, err := parseRecipient(item.To)
+	if err != nil {
+		return err
+	}
+
+	var msg *waProto.Message
+	switch {
+	case item.File != "":
+		caption := item.Caption
+		if caption == "" {
+			caption = item.Message
+		}
+		msg, err = buildFileMessage(ctx, client, item.File, caption)
+		if err != nil {
+			return err
+		}
+	case item.Message != "":
+		msg = &waProto.Message{Conversation: proto.String(item.Message)}
+	default:
+		return errors.New("pesan kosong")
+	}
+
+	resp, err := client.SendMessage(ctx, jid, msg)
+	if err != nil {
+		return fmt.Errorf("gagal kirim ke %s: %w", jid, err)
+	}
+	fmt.Printf("✅ Terkirim ke %s (id %s)\n", jid, resp.ID)
+	return nil
+}
+
+// parseRecipient menerima JID lengkap, ID grup atau nomor dalam format apa pun
+// (0898..., +62 898-1389-448, 628...). Lihat phone.NormalizeJID.
+func parseRecipient(s string) (types.JID, error) {
+	return phone.NormalizeJID(s)
+}
+
+// buildFileMessage meng-upload file lalu membungkusnya sesuai jenis media:
+// gambar, video dan audio dikirim sebagai media, selain itu sebagai dokumen.
+func buildFileMessage(ctx context.Context, client *whatsmeow.Client, path, caption string) (*waProto.Message, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("gagal baca file: %w", err)
+	}
+	mimetype := mime.TypeByExtension(filepath.Ext(path))
+	if mimetype == "" {
+		mimetype = http.DetectContentType(data)
+	}
+
+	mediaType := whatsmeow.MediaDocument
+	switch {
+	case strings.HasPrefix(mimetype, "image/"):
+		mediaType = whatsmeow.MediaImage
+	case strings.HasPrefix(mimetype, "video/"):
+		mediaType = whatsmeow.MediaVideo
+	case strings.HasPrefix(mimetype, "audio/"):
+		mediaType = whatsmeow.MediaAudio
+	}
+
+	up, err := client.Upload(ctx, data, mediaType)
+	if err != nil {
+		return nil, fmt.Errorf("gagal upload file: %w", err)
+	}
+
+	switch mediaType {
+	case whatsmeow.MediaImage:
+		return &waProto.Message{ImageMessage: &waProto.ImageMessage{
+			Caption:       optString(caption),
+			Mimetype:      proto.String(mimetype),
+			URL:           proto.String(up.URL),
+			DirectPath:    proto.String(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    proto.Uint64(up.FileLength),
+		}}, nil
+	case whatsmeow.MediaVideo:
+		return &waProto.Message{VideoMessage: &waProto.VideoMessage{
+			Caption:       optString(caption),
+			Mimetype:      proto.String(mimetype),
+			URL:           proto.String(up.URL),
+			DirectPath:    proto.String(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    proto.Uint64(up.FileLength),
+		}}, nil
+	case whatsmeow.MediaAudio:
+		return &waProto.Message{AudioMessage: &waProto.AudioMessage{
+			Mimetype:      proto.String(mimetype),
+			URL:           proto.String(up.URL),
+			DirectPath:    proto.String(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    proto.Uint64(up.FileLength),
+		}}, nil
+	}
+	return &waProto.Message{DocumentMessage: &waProto.DocumentMessage{
+		Title:         proto.String(filepath.Base(path)),
+		FileName:      proto.String(filepath.Base(path)),
+		Caption:       optString(caption),
+		Mimetype:      proto.String(mimetype),
+		URL:           proto.String(up.URL),
+		DirectPath:    proto.String(up.DirectPath),
+		MediaKey:      up.MediaKey,
+		FileEncSHA256: up.FileEncSHA256,
+		FileSHA256:    up.FileSHA256,
+		FileLength:    proto.Uint64(up.FileLength),
+	}}, nil
+}
+
+func optString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return st.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"google.golang.org/protobuf/proto"
+)
+
+const chatListWidth = 30
+
+type tuiMsg struct {
+	Time   time.Time
+	Sender string
+	FromMe bool
+	Text   string
+}
+
+type tuiChat struct {
+	JID      types.JID
+	Name     string
+	Last     time.Time
+	Unread   int
+	Messages []tuiMsg
+}
+
+// chatUI adalah state layar TUI. Semua field di bawah mu diakses dari event
+// handler whatsmeow maupun loop layar, jadi selalu pegang mu saat membaca/menulis.
+type chatUI struct {
+	client *whatsmeow.Client
+
+	mu       sync.Mutex
+	screen   tcell.Screen // nil sampai terminal siap; redraw membacanya dari goroutine whatsmeow
+	chats    map[types.JID]*tuiChat
+	order    []*tuiChat
+	selected types.JID
+	scroll   int
+	input    []rune
+	status   string
+}
+
+func runTUI(args []string) int {
+	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	ctx := context.Background()
+	client, err := openClient(ctx, waLog.Noop, waLog.Noop)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	if client.Store.ID == nil {
+		if err := pairWithQR(ctx, client); err != nil {
+			fmt.Fprintln(os.Stderr, "❌", err)
+			return 1
+		}
+		client.Disconnect()
+	}
+
+	ui := &chatUI{client: client, chats: map[types.JID]*tuiChat{}, status: "Menyambungkan..."}
+	client.AddEventHandler(ui.handleEvent)
+	if err := connectLoggedIn(client); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	defer client.Disconnect()
+	ui.loadGroups()
+
+	screen, err := tcell.NewScreen()
+	if err == nil {
+		err = screen.Init()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ gagal membuka terminal:", err)
+		return 1
+	}
+	defer screen.Fini()
+	ui.mu.Lock()
+	ui.screen = screen
+	ui.mu.Unlock()
+	ui.setStatus("✅ Login sebagai " + client.Store.ID.User + " — Tab/↑↓ pilih chat, Enter kirim, /to 628xxx chat baru, Esc keluar")
+	ui.run()
+	return 0
+}
+
+func (ui *chatUI) run() {
+	for {
+		ui.draw()
+		switch ev := ui.screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			ui.screen.Sync()
+		case *tcell.EventKey:
+			if !ui.handleKey(ev) {
+				return
+			}
+		}
+	}
+}
+
+// redraw meminta loop layar menggambar ulang dari goroutine lain.
+// Pemanggil tidak boleh sedang memegang mu.
+func (ui *chatUI) redraw() {
+	ui.mu.Lock()
+	s := ui.screen
+	ui.mu.Unlock()
+	if s != nil {
+		_ = s.PostEvent(tcell.NewEventInterrupt(nil))
+	}
+}
+
+func (ui *chatUI) setStatus(s string) {
+	ui.mu.Lock()
+	ui.status = s
+	ui.mu.Unlock()
+	ui.redraw()
+}
+
+/* ---------- WhatsApp ---------- */
+
+func (ui *chatUI) handleEvent(raw interface{}) {
+	switch v := raw.(type) {
+	case *events.Message:
+		text := messageText(v.Message)
+		if text == "" {
+			text = "<" + messageType(v.Message) + ">"
+		}
+		sender := v.Info.PushName
+		if sender == "" {
+			sender = v.Info.Sender.User
+		}
+		ui.addMessage(v.Info.Chat, tuiMsg{Time: v.Info.Timestamp, Sender: sender, FromMe: v.Info.IsFromMe, Text: text})
+	case *events.GroupInfo:
+		if v.Name != nil {
+			ui.mu.Lock()
+			ui.chat(v.JID).Name = v.Name.Name
+			ui.mu.Unlock()
+			ui.redraw()
+		}
+	case *events.Disconnected:
+		ui.setStatus("⚠️  Koneksi terputus, mencoba ulang...")
+	case *events.Connected:
+		ui.setStatus("✅ Tersambung")
+	case *events.LoggedOut:
+		ui.setStatus("🚪 Session logout dari HP, jalankan `wa-cli login` lagi")
+	}
+}
+
+func (ui *chatUI) loadGroups() {
+	groups, err := ui.client.GetJoinedGroups()
+	if err != nil {
+		return
+	}
+	ui.mu.Lock()
+	for _, g := range groups {
+		ui.chat(g.JID).Name = g.Name
+	}
+	ui.mu.Unlock()
+}
+
+func (ui *chatUI) addMessage(jid types.JID, m tuiMsg) {
+	ui.mu.Lock()
+	c := ui.chat(jid)
+	c.Messages = append(c.Messages, m)
+	if m.Time.After(c.Last) {
+		c.Last = m.Time
+	}
+	if !m.FromMe && jid != ui.selected {
+		c.Unread++
+	}
+	if ui.selected.IsEmpty() {
+		ui.selected = jid
+	}
+	ui.sortChats()
+	ui.mu.Unlock()
+	ui.redraw()
+}
+
+// chat mengambil atau membuat entri chat. Pemanggil harus memegang mu.
+func (ui *chatUI) chat(jid types.JID) *tuiChat {
+	jid = jid.ToNonAD()
+	if c, ok := ui.chats[jid]; ok {
+		return c
+	}
+	c := &tuiChat{JID: jid, Name: ui.contactName(jid)}
+	ui.chats[jid] = c
+	ui.order = append(ui.order, c)
+	ui.sortChats()
+	return c
+}
+
+func (ui *chatUI) contactName(jid types.JID) string {
+	if jid.Server == types.DefaultUserServer {
+		if info, err := ui.client.Store.Contacts.GetContact(context.Background(), jid); err == nil && info.Found {
+			for _, n := range []string{info.FullName, info.FirstName, info.BusinessName, info.PushName} {
+				if n != "" {
+					return n
+				}
+			}
+		}
+		return "+" + jid.User
+	}
+	return jid.User
+}
+
+func (ui *chatUI) sortChats() {
+	sort.SliceStable(ui.order, func(i, j int) bool {
+		return ui.order[i].Last.After(ui.order[j].Last)
+	})
+}
+
+func (ui *chatUI) send(jid types.JID, text string) {
+	_, err := ui.client.SendMessage(context.Background(), jid, &waProto.Message{Conversation: proto.String(text)})
+	if err != nil {
+		ui.setStatus("❌ Gagal kirim: " + err.Error())
+		return
+	}
+	ui.addMessage(jid, tuiMsg{Time: time.Now(), Sender: "Saya", FromMe: true, Text: text})
+}
+
+/* ---------- Keyboard ---------- */
+
+// handleKey memproses satu tombol. Mengembalikan false kalau user keluar.
+func (ui *chatUI) handleKey(ev *tcell.EventKey) bool {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	switch ev.Key() {
+	case tcell.KeyEscape, tcell.KeyCtrlC:
+		return false
+	case tcell.KeyUp, tcell.KeyBacktab:
+		ui.moveSelection(-1)
+	case tcell.KeyDown, tcell.KeyTab:
+		ui.moveSelection(1)
+	case tcell.KeyPgUp:
+		ui.scroll += 5
+	case tcell.KeyPgDn:
+		ui.scroll = max(0, ui.scroll-5)
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(ui.input) > 0 {
+			ui.input = ui.input[:len(ui.input)-1]
+		}
+	case tcell.KeyEnter:
+		ui.submit()
+	case tcell.KeyRune:
+		ui.input = append(ui.input, ev.Rune())
+	}
+	return true
+}
+
+// submit dipanggil dengan mu terkunci.
+func (ui *chatUI) submit() {
+	text := strings.TrimSpace(string(ui.input))
+	ui.input = nil
+	if text == "" {
+		return
+	}
+	if strings.HasPrefix(text, "/to ") {
+		jid, err := parseRecipient(strings.TrimPrefix(text, "/to "))
+		if err != nil {
+			ui.status = "❌ " + err.Error()
+			return
+		}
+		ui.chat(jid)
+		ui.selected = jid.ToNonAD()
+		ui.scroll = 0
+		return
+	}
+	if ui.selected.IsEmpty() {
+		ui.status = "❌ Belum ada chat terpilih, pakai /to 628xxx"
+		return
+	}
+	go ui.send(ui.selected, text)
+}
+
+// moveSelection dipanggil dengan mu terkunci.
+func (ui *chatUI) moveSelection(delta int) {
+	if len(ui.order) == 0 {
+		return
+	}
+	idx := 0
+	for i, c := range ui.order {
+		if c.JID == ui.selected {
+			idx = i
+			break
+		}
+	}
+	idx = (idx + delta + len(ui.order)) % len(ui.order)
+	ui.selected = ui.order[idx].JID
+	ui.order[idx].Unread = 0
+	ui.scroll = 0
+}
+
+/* ---------- Drawing ---------- */
+
+func (ui *chatUI) draw() {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	s := ui.screen
+	s.Clear()
+	w, h := s.Size()
+	if w < chatListWidth+20 || h < 6 {
+		drawText(s, 0, 0, w, tcell.StyleDefault, "Terminal terlalu kecil")
+		s.Show()
+		return
+	}
+
+	normal := tcell.StyleDefault
+	dim := normal.Foreground(tcell.ColorGray)
+	bold := normal.Bold(true)
+	highlight := normal.Reverse(true)
+
+	// daftar chat
+	for y := 0; y < h-2; y++ {
+		s.SetContent(chatListWidth, y, '│', nil, dim)
+	}
+	for i, c := range ui.order {
+		if i >= h-2 {
+			break
+		}
+		style := normal
+		if c.JID == ui.selected {
+			style = highlight
+		}
+		label := c.Name
+		if c.JID.Server == types.GroupServer {
+			label = "👥 " + label
+		}
+		if c.Unread > 0 {
+			label = fmt.Sprintf("(%d) %s", c.Unread, label)
+		}
+		drawText(s, 0, i, chatListWidth, style, padRight(label, chatListWidth))
+	}
+
+	// pesan chat terpilih
+	paneX := chatListWidth + 2
+	paneW := w - paneX
+	paneH := h - 3
+	if c, ok := ui.chats[ui.selected]; ok {
+		c.Unread = 0
+		drawText(s, paneX, 0, paneW, bold, c.Name)
+		var lines []string
+		var styles []tcell.Style
+		for _, m := range c.Messages {
+			prefix := m.Time.Local().Format("15:04") + " "
+			if m.FromMe {
+				prefix += "Saya: "
+			} else if c.JID.Server == types.GroupServer {
+				prefix += m.Sender + ": "
+			}
+			for _, l := range wrapText(prefix+m.Text, paneW) {
+				lines = append(lines, l)
+				if m.FromMe {
+					styles = append(styles, dim)
+				} else {
+					styles = append(styles, normal)
+				}
+			}
+		}
+		end := len(lines) - ui.scroll
+		if end < 0 {
+			end = 0
+		}
+		start := max(0, end-(paneH-1))
+		for i := start; i < end; i++ {
+			drawText(s, paneX, 1+i-start, paneW, styles[i], lines[i])
+		}
+	}
+
+	// input & status
+	for x := 0; x < w; x++ {
+		s.SetContent(x, h-2, '─', nil, dim)
+	}
+	prompt := "> " + string(ui.input)
+	drawText(s, 0, h-1, w, normal, prompt)
+	s.ShowCursor(min(runewidth.StringWidth(prompt), w-1), h-1)
+	drawText(s, paneX, h-3, paneW, dim, ui.status)
+	s.Show()
+}
+
+func drawText(s tcell.Screen, x, y, maxW int, style tcell.Style, text string) {
+	col := 0
+	for _, r := range text {
+		rw := runewidth.RuneWidth(r)
+		if col+rw > maxW {
+			break
+		}
+		s.SetContent(x+col, y, r, nil, style)
+		col += rw
+	}
+}
+
+func padRight(s string, w int) string {
+	if n := runewidth.StringWidth(s); n < w {
+		return s + strings.Repeat(" ", w-n)
+	}
+	return s
+}
+
+// wrapText memecah teks per baris layar, termasuk baris baru di dalam pesan.
+func wrapText(text string, width int) []string {
+	var out []string
+	for _, para := range strings.Split(text, "\n") {
+		line, col := []rune{}, 0
+		for _, r := range para {
+			rw := runewidth.RuneWidth(r)
+			if col+rw > width {
+				out = append(out, string(line))
+				line, col = nil, 0
+			}
+			line = append(line, r)
+			col += rw
+		}
+		out = append(out, string(line))
+	}
+	return out
+}
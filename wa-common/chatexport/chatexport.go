@@ -0,0 +1,409 @@
+// Package chatexport membaca satu chat dari arsip pesan gateway.db (tabel
+// messages/chats) dan merendernya ke JSON, CSV, HTML atau TXT ala WhatsApp.
+// Dipakai gateway (GET /chats/{jid}/export) dan `wa-cli export`.
+package chatexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// Formats memetakan format yang didukung ke Content-Type-nya.
+var Formats = map[string]string{
+	"json": "application/json",
+	"csv":  "text/csv; charset=utf-8",
+	"html": "text/html; charset=utf-8",
+	"txt":  "text/plain; charset=utf-8",
+}
+
+// ErrChatNotFound dikembalikan Load kalau chat belum ada di arsip.
+var ErrChatNotFound = errors.New("chat tidak ada di arsip")
+
+// Options memilih chat, rentang waktu dan bentuk output export.
+type Options struct {
+	Chat     string // JID lengkap
+	From, To time.Time
+	Format   string // json, csv, html, txt
+	Zip      bool   // bungkus transkrip (dan media) dalam zip
+	Media    bool   // ikut download media ke zip; butuh Zip
+	SelfName string // nama untuk pesan dari kita sendiri
+	Location *time.Location
+}
+
+// Message adalah satu baris transkrip.
+type Message struct {
+	ID        string    `json:"id"`
+	Sender    string    `json:"sender"`
+	Name      string    `json:"name"`
+	FromMe    bool      `json:"from_me"`
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	Text      string    `json:"text,omitempty"`
+	Mimetype  string    `json:"mimetype,omitempty"`
+	File      string    `json:"file,omitempty"` // nama file media di dalam zip
+	Edited    bool      `json:"edited,omitempty"`
+	Revoked   bool      `json:"revoked,omitempty"`
+	Thumbnail []byte    `json:"-"`
+
+	media *waProto.Message
+	path  string // lampiran lokal hasil import ekspor WhatsApp (kolom file)
+}
+
+func (m *Message) hasMedia() bool { return m.media != nil || m.path != "" }
+
+// Chat adalah transkrip satu chat hasil Load.
+type Chat struct {
+	JID      string     `json:"jid"`
+	Name     string     `json:"name"`
+	From     *time.Time `json:"from,omitempty"`
+	To       *time.Time `json:"to,omitempty"`
+	Messages []Message  `json:"messages"`
+}
+
+// ParseTime menerima "2006-01-02" atau RFC3339. Untuk batas akhir,
+// tanggal saja berarti sampai akhir hari itu.
+func ParseTime(s string, end bool, loc *time.Location) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("tanggal %q harus YYYY-MM-DD atau RFC3339", s)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+// Load mengambil pesan satu chat dari arsip, urut dari yang terlama. Error
+// selain ErrChatNotFound berasal dari database.
+func Load(db *sql.DB, opt Options) (*Chat, error) {
+	if opt.Location == nil {
+		opt.Location = time.Local
+	}
+	chat := &Chat{JID: opt.Chat, Messages: []Message{}}
+	err := db.QueryRow(`SELECT name FROM chats WHERE jid = ?`, opt.Chat).Scan(&chat.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrChatNotFound, opt.Chat)
+	} else if err != nil {
+		return nil, err
+	}
+	if chat.Name == "" {
+		chat.Name = strings.SplitN(opt.Chat, "@", 2)[0]
+	}
+
+	query := `SELECT id, sender, push_name, from_me, time, type, text, mimetype, media, file, edited, revoked
+		FROM messages WHERE chat = ?`
+	args := []interface{}{opt.Chat}
+	if !opt.From.IsZero() {
+		query += ` AND time >= ?`
+		args = append(args, opt.From.Unix())
+		from := opt.From
+		chat.From = &from
+	}
+	if !opt.To.IsZero() {
+		query += ` AND time <= ?`
+		args = append(args, opt.To.Unix())
+		to := opt.To
+		chat.To = &to
+	}
+	rows, err := db.Query(query+` ORDER BY time, rowid`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	selfName := opt.SelfName
+	if selfName == "" {
+		selfName = "Saya"
+	}
+	for rows.Next() {
+		var m Message
+		var ts int64
+		var raw []byte
+		if err := rows.Scan(&m.ID, &m.Sender, &m.Name, &m.FromMe, &ts, &m.Type, &m.Text, &m.Mimetype, &raw, &m.path, &m.Edited, &m.Revoked); err != nil {
+			return nil, err
+		}
+		m.Time = time.Unix(ts, 0).In(opt.Location)
+		switch {
+		case m.FromMe:
+			m.Name = selfName
+		case m.Name == "":
+			m.Name = "+" + strings.SplitN(m.Sender, "@", 2)[0]
+		}
+		if len(raw) > 0 {
+			var media waProto.Message
+			if proto.Unmarshal(raw, &media) == nil {
+				m.media = &media
+				m.Thumbnail = mediaThumbnail(&media)
+			}
+		}
+		chat.Messages = append(chat.Messages, m)
+	}
+	return chat, rows.Err()
+}
+
+func mediaThumbnail(m *waProto.Message) []byte {
+	switch {
+	case m.GetImageMessage() != nil:
+		return m.GetImageMessage().GetJPEGThumbnail()
+	case m.GetVideoMessage() != nil:
+		return m.GetVideoMessage().GetJPEGThumbnail()
+	case m.GetDocumentMessage() != nil:
+		return m.GetDocumentMessage().GetJPEGThumbnail()
+	}
+	return nil
+}
+
+// Write menulis transkrip ke w. Dengan opt.Zip hasilnya zip berisi
+// transkrip dan (kalau opt.Media) folder media/: lampiran hasil import dibaca
+// dari disk, media WhatsApp didownload lewat download kalau tidak nil.
+// Media yang gagal dibaca atau didownload dilewati tanpa membatalkan export;
+// transkrip menandainya sebagai tidak disertakan. Zip ditulis langsung ke w tanpa
+// buffer, jadi error di tengah jalan berarti output sudah terpotong.
+func Write(w io.Writer, chat *Chat, opt Options, download func(*waProto.Message) ([]byte, error)) error {
+	if _, ok := Formats[opt.Format]; !ok {
+		return fmt.Errorf("format harus json, csv, html atau txt")
+	}
+	if !opt.Zip {
+		return renderExport(w, chat, opt.Format)
+	}
+
+	zw := zip.NewWriter(w)
+	if opt.Media {
+		for i := range chat.Messages {
+			m := &chat.Messages[i]
+			if m.Revoked {
+				continue
+			}
+			var err error
+			switch {
+			case m.path != "":
+				err = zipLocalFile(zw, i+1, m)
+			case m.media != nil && download != nil:
+				err = zipDownload(zw, i+1, m, download)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	f, err := zw.Create("chat." + opt.Format)
+	if err != nil {
+		return err
+	}
+	if err := renderExport(f, chat, opt.Format); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// zipLocalFile menyalin lampiran import ke zip tanpa menampungnya di
+// memori. File yang sudah tidak ada dilewati.
+func zipLocalFile(zw *zip.Writer, seq int, m *Message) error {
+	src, err := os.Open(m.path)
+	if err != nil {
+		return nil
+	}
+	defer src.Close()
+	name := fmt.Sprintf("%08d-%s", seq, filepath.Base(m.path))
+	f, err := zw.Create("media/" + name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		return err
+	}
+	m.File = name
+	return nil
+}
+
+// zipDownload menaruh media WhatsApp ke zip; yang gagal didownload dilewati.
+func zipDownload(zw *zip.Writer, seq int, m *Message, download func(*waProto.Message) ([]byte, error)) error {
+	data, err := download(m.media)
+	if err != nil {
+		return nil
+	}
+	m.File = mediaFileName(seq, m)
+	f, err := zw.Create("media/" + m.File)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func renderExport(w io.Writer, chat *Chat, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(chat)
+	case "csv":
+		return renderCSV(w, chat)
+	case "html":
+		return exportHTML.Execute(w, chat)
+	default:
+		return renderTXT(w, chat)
+	}
+}
+
+func renderCSV(w io.Writer, chat *Chat) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"time", "id", "sender", "name", "from_me", "type", "text", "file", "edited", "revoked"})
+	for _, m := range chat.Messages {
+		_ = cw.Write([]string{
+			m.Time.Format(time.RFC3339), m.ID, m.Sender, m.Name, strconv.FormatBool(m.FromMe),
+			m.Type, m.Text, m.File, strconv.FormatBool(m.Edited), strconv.FormatBool(m.Revoked),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// renderTXT meniru format ekspor bawaan WhatsApp (locale Indonesia):
+//
+//	[18/10/25, 19.05.12] Budi: halo
+//	[18/10/25, 19.06.40] Budi: <terlampir: 00000002-PHOTO-2025-10-18-19-06-40.jpg>
+func renderTXT(w io.Writer, chat *Chat) error {
+	var b bytes.Buffer
+	for _, m := range chat.Messages {
+		fmt.Fprintf(&b, "[%s] %s: ", m.Time.Format("02/01/06, 15.04.05"), m.Name)
+		switch {
+		case m.Revoked:
+			b.WriteString("Pesan ini telah dihapus")
+		case m.File != "":
+			fmt.Fprintf(&b, "<terlampir: %s>", m.File)
+			if m.Text != "" {
+				b.WriteString("\n" + m.Text)
+			}
+		case m.hasMedia() && m.Text == "":
+			fmt.Fprintf(&b, "<%s tidak disertakan>", mediaKind(m.Type))
+		default:
+			b.WriteString(m.Text)
+		}
+		if m.Edited {
+			b.WriteString(" <Pesan ini diedit>")
+		}
+		b.WriteByte('\n')
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// mediaFileName mengikuti pola nama file di ekspor WhatsApp,
+// mis. 00000012-PHOTO-2025-10-18-19-06-40.jpg.
+func mediaFileName(seq int, m *Message) string {
+	kind := map[string]string{"image": "PHOTO", "video": "VIDEO", "audio": "AUDIO", "sticker": "STICKER"}[m.Type]
+	if kind == "" {
+		if name := m.media.GetDocumentMessage().GetFileName(); name != "" {
+			return fmt.Sprintf("%08d-%s", seq, strings.ReplaceAll(name, "/", "_"))
+		}
+		kind = "DOCUMENT"
+	}
+	return fmt.Sprintf("%08d-%s-%s%s", seq, kind, m.Time.Format("2006-01-02-15-04-05"), mimeExtension(m.Mimetype))
+}
+
+func mediaKind(typ string) string {
+	switch typ {
+	case "image":
+		return "gambar"
+	case "video":
+		return "video"
+	case "audio":
+		return "audio"
+	case "sticker":
+		return "stiker"
+	}
+	return "dokumen"
+}
+
+func mimeExtension(mimetype string) string {
+	base := strings.TrimSpace(strings.SplitN(mimetype, ";", 2)[0])
+	switch base {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	case "video/mp4":
+		return ".mp4"
+	case "audio/ogg":
+		return ".opus"
+	case "application/pdf":
+		return ".pdf"
+	}
+	if exts, _ := mime.ExtensionsByType(base); len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+var exportHTML = template.Must(template.New("export").Funcs(template.FuncMap{
+	"thumb": func(b []byte) template.URL {
+		return template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(b))
+	},
+	"day":   func(t time.Time) string { return t.Format("Monday, 02 January 2006") },
+	"clock": func(t time.Time) string { return t.Format("15.04") },
+	"newDay": func(msgs []Message, i int) bool {
+		return i == 0 || msgs[i-1].Time.Format("2006-01-02") != msgs[i].Time.Format("2006-01-02")
+	},
+	"kind": mediaKind,
+}).Parse(`<!DOCTYPE html>
+<html lang="id">
+<head>
+<meta charset="utf-8">
+<title>Chat {{.Name}}</title>
+<style>
+body{font-family:system-ui,sans-serif;background:#efeae2;margin:0;padding:16px}
+h1{font-size:18px;margin:0 0 12px}
+.day{text-align:center;margin:16px 0 8px;color:#54656f;font-size:12px}
+.msg{max-width:70%;margin:4px 0;padding:6px 10px;border-radius:8px;background:#fff;box-shadow:0 1px 1px #0002;clear:both}
+.me{margin-left:auto;background:#d9fdd3}
+.name{font-size:12px;font-weight:600;color:#1f7aec}
+.text{white-space:pre-wrap;word-wrap:break-word}
+.meta{font-size:11px;color:#667781;text-align:right}
+.gone{font-style:italic;color:#667781}
+img{max-width:240px;border-radius:6px;display:block;margin:4px 0}
+</style>
+</head>
+<body>
+<h1>{{.Name}} <small>({{.JID}})</small></h1>
+{{- $msgs := .Messages}}
+{{- range $i, $m := .Messages}}
+{{- if newDay $msgs $i}}<div class="day">{{day $m.Time}}</div>{{end}}
+<div class="msg{{if $m.FromMe}} me{{end}}">
+{{- if not $m.FromMe}}<div class="name">{{$m.Name}}</div>{{end}}
+{{- if $m.Revoked}}<div class="text gone">Pesan ini telah dihapus</div>
+{{- else}}
+{{- if $m.Thumbnail}}{{if $m.File}}<a href="media/{{$m.File}}">{{end}}<img src="{{thumb $m.Thumbnail}}" alt="{{$m.Type}}">{{if $m.File}}</a>{{end}}
+{{- else if $m.File}}<div><a href="media/{{$m.File}}">{{$m.File}}</a></div>
+{{- else if and $m.Mimetype (not $m.Text)}}<div class="gone">&lt;{{kind $m.Type}}&gt;</div>{{end}}
+{{- if $m.Text}}<div class="text">{{$m.Text}}</div>{{end}}
+{{- end}}
+<div class="meta">{{if $m.Edited}}diedit · {{end}}{{clock $m.Time}}</div>
+</div>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,197 @@
+package chatexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+const testChat = "628123456789@s.whatsapp.net"
+
+// openTestArchive membuat arsip dengan skema yang sama seperti gateway:
+// satu pesan teks, satu media WhatsApp dan satu lampiran hasil import.
+func openTestArchive(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "gateway.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	attachment := filepath.Join(dir, "IMG-20251018-WA0001.jpg")
+	if err := os.WriteFile(attachment, []byte("jpeg dari import"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	media, _ := proto.Marshal(&waProto.Message{ImageMessage: &waProto.ImageMessage{Mimetype: proto.String("image/jpeg")}})
+	for _, q := range []string{
+		`CREATE TABLE messages (
+			chat TEXT NOT NULL, id TEXT NOT NULL, sender TEXT NOT NULL, push_name TEXT NOT NULL DEFAULT '',
+			from_me INTEGER NOT NULL, time INTEGER NOT NULL, type TEXT NOT NULL, text TEXT NOT NULL DEFAULT '',
+			mimetype TEXT NOT NULL DEFAULT '', media BLOB, edited INTEGER NOT NULL DEFAULT 0,
+			revoked INTEGER NOT NULL DEFAULT 0, file TEXT NOT NULL DEFAULT '', PRIMARY KEY (chat, id))`,
+		`CREATE TABLE chats (jid TEXT PRIMARY KEY, name TEXT NOT NULL DEFAULT '', is_group INTEGER NOT NULL, last_time INTEGER NOT NULL)`,
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO chats VALUES (?, 'Budi', 0, 0)`, testChat); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []struct {
+		id, typ, text, mimetype string
+		time                    int64
+		media                   []byte
+		file                    string
+	}{
+		{id: "a", typ: "text", text: "halo", time: 1760788800},
+		{id: "b", typ: "image", mimetype: "image/jpeg", time: 1760788860, media: media},
+		{id: "c", typ: "image", mimetype: "image/jpeg", time: 1760788920, file: attachment},
+	} {
+		if _, err := db.Exec(`INSERT INTO messages (chat, id, sender, push_name, from_me, time, type, text, mimetype, media, file)
+			VALUES (?, ?, ?, 'Budi', 0, ?, ?, ?, ?, ?, ?)`,
+			testChat, m.id, testChat, m.time, m.typ, m.text, m.mimetype, m.media, m.file); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db, attachment
+}
+
+func TestLoad(t *testing.T) {
+	db, attachment := openTestArchive(t)
+	chat, err := Load(db, Options{Chat: testChat, Location: time.UTC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chat.Name != "Budi" || len(chat.Messages) != 3 {
+		t.Fatalf("Load = %q with %d messages, want Budi with 3", chat.Name, len(chat.Messages))
+	}
+	tests := []struct {
+		id       string
+		media    bool
+		path     string
+		hasMedia bool
+	}{
+		{id: "a"},
+		{id: "b", media: true, hasMedia: true},
+		{id: "c", path: attachment, hasMedia: true},
+	}
+	for i, tt := range tests {
+		m := chat.Messages[i]
+		if m.ID != tt.id || (m.media != nil) != tt.media || m.path != tt.path || m.hasMedia() != tt.hasMedia {
+			t.Errorf("message %d = %s media=%v path=%q; want %s media=%v path=%q",
+				i, m.ID, m.media != nil, m.path, tt.id, tt.media, tt.path)
+		}
+	}
+
+	if _, err := Load(db, Options{Chat: "620000000000@s.whatsapp.net"}); !errors.Is(err, ErrChatNotFound) {
+		t.Errorf("Load unknown chat: err = %v, want ErrChatNotFound", err)
+	}
+	from := time.Unix(1760788860, 0)
+	chat, err = Load(db, Options{Chat: testChat, From: from})
+	if err != nil || len(chat.Messages) != 2 {
+		t.Errorf("Load from %v: %d messages, %v; want 2", from, len(chat.Messages), err)
+	}
+}
+
+func TestWriteTXT(t *testing.T) {
+	db, _ := openTestArchive(t)
+	chat, err := Load(db, Options{Chat: testChat, Location: time.UTC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, chat, Options{Format: "txt"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "[18/10/25, 12.00.00] Budi: halo\n" +
+		"[18/10/25, 12.01.00] Budi: <gambar tidak disertakan>\n" +
+		"[18/10/25, 12.02.00] Budi: <gambar tidak disertakan>\n"
+	if buf.String() != want {
+		t.Errorf("txt =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteZipMedia(t *testing.T) {
+	tests := []struct {
+		name     string
+		download func(*waProto.Message) ([]byte, error)
+		files    map[string]string // nama di media/ -> isi
+	}{
+		{
+			name:     "download berhasil",
+			download: func(*waProto.Message) ([]byte, error) { return []byte("jpeg dari whatsapp"), nil },
+			files: map[string]string{
+				"media/00000002-PHOTO-2025-10-18-12-01-00.jpg": "jpeg dari whatsapp",
+				"media/00000003-IMG-20251018-WA0001.jpg":       "jpeg dari import",
+			},
+		},
+		{
+			name:     "download gagal",
+			download: func(*waProto.Message) ([]byte, error) { return nil, errors.New("offline") },
+			files:    map[string]string{"media/00000003-IMG-20251018-WA0001.jpg": "jpeg dari import"},
+		},
+		{
+			name:  "tanpa session",
+			files: map[string]string{"media/00000003-IMG-20251018-WA0001.jpg": "jpeg dari import"},
+		},
+	}
+	for _, tt := range tests {
+		db, _ := openTestArchive(t)
+		chat, err := Load(db, Options{Chat: testChat, Location: time.UTC})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := Write(&buf, chat, Options{Format: "json", Zip: true, Media: true}, tt.download); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := map[string]string{}
+		var transcript Chat
+		for _, f := range zr.File {
+			rc, _ := f.Open()
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			if f.Name == "chat.json" {
+				if err := json.Unmarshal(data, &transcript); err != nil {
+					t.Fatalf("%s: chat.json: %v", tt.name, err)
+				}
+				continue
+			}
+			got[f.Name] = string(data)
+		}
+		if len(got) != len(tt.files) {
+			t.Errorf("%s: zip media = %v, want %v", tt.name, got, tt.files)
+		}
+		for name, data := range tt.files {
+			if got[name] != data {
+				t.Errorf("%s: %s = %q, want %q", tt.name, name, got[name], data)
+			}
+		}
+		for _, m := range transcript.Messages {
+			if m.File != "" && tt.files["media/"+m.File] == "" {
+				t.Errorf("%s: message %s points to %q which is not in the zip", tt.name, m.ID, m.File)
+			}
+		}
+		if f := transcript.Messages[2].File; !strings.HasSuffix(f, "IMG-20251018-WA0001.jpg") {
+			t.Errorf("%s: imported attachment file = %q", tt.name, f)
+		}
+	}
+}
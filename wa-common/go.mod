@@ -0,0 +1,16 @@
+module wa-common
+
+go 1.23.0
+
+require (
+	github.com/mattn/go-sqlite3 v1.14.28
+	go.mau.fi/whatsmeow v0.0.0-20250722194234-b61df67bf925
+	google.golang.org/protobuf v1.36.6
+)
+
+require (
+	filippo.io/edwards25519 v1.1.0 // indirect
+	go.mau.fi/libsignal v0.2.0 // indirect
+	go.mau.fi/util v0.8.8 // indirect
+	golang.org/x/crypto v0.40.0 // indirect
+)
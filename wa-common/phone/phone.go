@@ -0,0 +1,189 @@
+// Package phone menormalkan nomor telepon dan JID WhatsApp. Dipakai bersama
+// oleh gateway dan wa-cli supaya keduanya menerima format nomor yang sama.
+package phone
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// DefaultCountryCode dipakai untuk nomor lokal (0898... atau 898...).
+// Bisa diganti lewat env DEFAULT_COUNTRY_CODE.
+const DefaultCountryCode = "62"
+
+// numberLength adalah panjang nomor nasional (tanpa kode negara) yang valid.
+type numberLength struct{ min, max int }
+
+// countryLengths berisi negara yang sering muncul di trafik kita. Negara lain
+// hanya dicek terhadap batas umum E.164 (8-15 digit).
+var countryLengths = map[string]numberLength{
+	"1":   {10, 10}, // US/Kanada (NANP)
+	"7":   {10, 10}, // Rusia/Kazakhstan
+	"31":  {9, 9},   // Belanda
+	"33":  {9, 9},   // Prancis
+	"44":  {10, 10}, // Inggris
+	"49":  {6, 13},  // Jerman
+	"60":  {9, 10},  // Malaysia
+	"61":  {9, 9},   // Australia
+	"62":  {8, 12},  // Indonesia
+	"63":  {10, 10}, // Filipina
+	"65":  {8, 8},   // Singapura
+	"66":  {8, 9},   // Thailand
+	"81":  {9, 10},  // Jepang
+	"82":  {8, 10},  // Korea Selatan
+	"84":  {9, 10},  // Vietnam
+	"86":  {11, 11}, // Tiongkok
+	"90":  {10, 10}, // Turki
+	"91":  {10, 10}, // India
+	"852": {8, 8},   // Hong Kong
+	"966": {9, 9},   // Arab Saudi
+	"971": {8, 9},   // UEA
+}
+
+// CountryCode mengembalikan kode negara default tanpa "+".
+func CountryCode() string {
+	if cc := strings.TrimPrefix(strings.TrimSpace(os.Getenv("DEFAULT_COUNTRY_CODE")), "+"); cc != "" {
+		return cc
+	}
+	return DefaultCountryCode
+}
+
+// Normalize mengubah format nomor apa pun ("08981389448", "8981389448",
+// "+62 898-1389-448", "+62 0898...", "0062...", "628981389448") menjadi
+// digit internasional tanpa "+", lalu memvalidasi panjangnya per negara.
+//
+// Nomor tanpa "+", "00" atau "0" dianggap internasional kalau cocok dengan
+// kode negara di countryLengths; kalau tidak, dicoba sebagai nomor nasional
+// dengan kode negara default. Nomor internasional dari negara di luar
+// daftar sebaiknya ditulis dengan "+" atau "00" supaya tidak tertukar.
+func Normalize(input string) (string, error) {
+	return normalize(input, true)
+}
+
+func normalize(input string, local bool) (string, error) {
+	s := strings.TrimSpace(input)
+	international := strings.HasPrefix(s, "+")
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ', r == '-', r == '.', r == '(', r == ')', r == '+' && b.Len() == 0:
+		default:
+			return "", fmt.Errorf("invalid phone number: %q", input)
+		}
+	}
+	digits := b.String()
+
+	switch {
+	case international:
+	case strings.HasPrefix(digits, "00"):
+		digits, international = digits[2:], true
+	case strings.HasPrefix(digits, "0"):
+		digits, international = CountryCode()+strings.TrimLeft(digits, "0"), true
+	}
+	if digits == "" {
+		return "", fmt.Errorf("invalid phone number: %q", input)
+	}
+	digits = trimTrunkPrefix(digits)
+
+	cc, rule, known := countryOf(digits)
+	if known && validNational(len(digits)-len(cc), rule) {
+		return digits, nil
+	}
+	// Nomor nasional tanpa 0 di depan, mis. 8981389448.
+	if local && !international {
+		if rule, ok := countryLengths[CountryCode()]; ok && validNational(len(digits), rule) {
+			return CountryCode() + digits, nil
+		}
+	}
+	if known {
+		return "", fmt.Errorf("invalid phone number %q: +%s numbers have %d-%d digits after the country code", input, cc, rule.min, rule.max)
+	}
+	if len(digits) < 8 || len(digits) > 15 {
+		return "", fmt.Errorf("invalid phone number %q: expected 8-15 digits including country code", input)
+	}
+	return digits, nil
+}
+
+// countryOf mencari kode negara terpanjang di countryLengths yang menjadi
+// awalan digits.
+func countryOf(digits string) (string, numberLength, bool) {
+	for l := 3; l >= 1; l-- {
+		if len(digits) <= l {
+			continue
+		}
+		if rule, ok := countryLengths[digits[:l]]; ok {
+			return digits[:l], rule, true
+		}
+	}
+	return "", numberLength{}, false
+}
+
+func validNational(n int, rule numberLength) bool {
+	return n >= rule.min && n <= rule.max
+}
+
+// trimTrunkPrefix membuang 0 awalan lokal yang ikut ditulis setelah kode
+// negara, mis. "+62 0898..." menjadi 62898.... Hanya untuk negara di
+// countryLengths; semuanya tidak memakai 0 di nomor internasional.
+func trimTrunkPrefix(digits string) string {
+	cc, _, ok := countryOf(digits)
+	if !ok || !strings.HasPrefix(digits[len(cc):], "0") {
+		return digits
+	}
+	return cc + strings.TrimLeft(digits[len(cc):], "0")
+}
+
+// NormalizeJID menerima JID lengkap, ID grup (120363...@g.us, 120363...,
+// 628xxx-1600000000) atau nomor telepon dalam format manusia.
+func NormalizeJID(input string) (types.JID, error) {
+	s := strings.TrimSpace(input)
+	if strings.Contains(s, "@") {
+		jid, err := types.ParseJID(s)
+		if err != nil || jid.User == "" {
+			return types.EmptyJID, fmt.Errorf("invalid JID: %q", input)
+		}
+		if jid.Server == types.DefaultUserServer || jid.Server == types.LegacyUserServer {
+			// User di JID selalu sudah internasional.
+			phone, err := normalize(jid.User, false)
+			if err != nil {
+				return types.EmptyJID, err
+			}
+			return types.NewJID(phone, types.DefaultUserServer), nil
+		}
+		return jid, nil
+	}
+	if isGroupID(s) {
+		return types.NewJID(s, types.GroupServer), nil
+	}
+	phone, err := Normalize(s)
+	if err != nil {
+		return types.EmptyJID, err
+	}
+	return types.NewJID(phone, types.DefaultUserServer), nil
+}
+
+// isGroupID mengenali ID grup tanpa server: format lama "pembuat-timestamp"
+// atau ID baru yang lebih panjang dari batas nomor E.164.
+func isGroupID(s string) bool {
+	if owner, ts, ok := strings.Cut(s, "-"); ok {
+		return allDigits(owner) && allDigits(ts) && len(ts) == 10
+	}
+	return allDigits(s) && len(s) > 15
+}
+
+func allDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
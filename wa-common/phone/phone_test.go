@@ -0,0 +1,101 @@
+package phone
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Setenv("DEFAULT_COUNTRY_CODE", "")
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "08981389448", want: "628981389448"},
+		{in: "8981389448", want: "628981389448"},
+		{in: "628981389448", want: "628981389448"},
+		{in: "+62 898-1389-448", want: "628981389448"},
+		{in: "+62 0898 1389 448", want: "628981389448"},
+		{in: "+62 (0)898 1389 448", want: "628981389448"},
+		{in: "0062 898 1389 448", want: "628981389448"},
+		{in: "0062 0898 1389 448", want: "628981389448"},
+		{in: "62 0898 1389 448", want: "628981389448"},
+		{in: "  0898.1389.448  ", want: "628981389448"},
+		{in: "8123456789", want: "628123456789"}, // bukan +81 karena kepanjangan untuk Jepang
+		{in: "+1 (415) 555-2671", want: "14155552671"},
+		{in: "14155552671", want: "14155552671"},
+		{in: "+44 07911 123456", want: "447911123456"},
+		{in: "+60 12-345 6789", want: "60123456789"},
+		{in: "+234 803 123 4567", want: "2348031234567"}, // di luar daftar, cek E.164 saja
+		{in: "", wantErr: true},
+		{in: "+", wantErr: true},
+		{in: "0", wantErr: true},
+		{in: "0812", wantErr: true},
+		{in: "+62 812", wantErr: true},
+		{in: "+1 415 555 267", wantErr: true},
+		{in: "0812-abc-4567", wantErr: true},
+		{in: "0812+3456789", wantErr: true},
+		{in: "+1234567", wantErr: true},
+		{in: "1234567890123456", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Normalize(%q) = %q, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeCountryCodeEnv(t *testing.T) {
+	t.Setenv("DEFAULT_COUNTRY_CODE", "+60")
+	for in, want := range map[string]string{
+		"012-345 6789": "60123456789",
+		"123456789":    "60123456789",
+		"628981389448": "628981389448",
+	} {
+		if got, err := Normalize(in); err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestNormalizeJID(t *testing.T) {
+	t.Setenv("DEFAULT_COUNTRY_CODE", "")
+	tests := []struct {
+		in      string
+		want    types.JID
+		wantErr bool
+	}{
+		{in: "08981389448", want: types.NewJID("628981389448", types.DefaultUserServer)},
+		{in: "628981389448@s.whatsapp.net", want: types.NewJID("628981389448", types.DefaultUserServer)},
+		{in: "628981389448@c.us", want: types.NewJID("628981389448", types.DefaultUserServer)},
+		// User di JID sudah internasional, jangan diberi kode negara default.
+		{in: "551187654321@s.whatsapp.net", want: types.NewJID("551187654321", types.DefaultUserServer)},
+		{in: "120363025246125486@g.us", want: types.NewJID("120363025246125486", types.GroupServer)},
+		{in: "120363025246125486", want: types.NewJID("120363025246125486", types.GroupServer)},
+		{in: "628981389448-1600000000", want: types.NewJID("628981389448-1600000000", types.GroupServer)},
+		{in: "123456789012345@lid", want: types.NewJID("123456789012345", types.HiddenUserServer)},
+		{in: "@s.whatsapp.net", wantErr: true},
+		{in: "12@s.whatsapp.net", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeJID(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NormalizeJID(%q) = %s, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeJID(%q) = %s, %v; want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
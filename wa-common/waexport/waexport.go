@@ -0,0 +1,236 @@
+// Package waexport membaca file teks hasil fitur "Ekspor chat" WhatsApp
+// (_chat.txt di iPhone, "WhatsApp Chat with X.txt" di Android) dalam locale
+// Indonesia maupun Inggris.
+package waexport
+
+import (
+	"archive/zip"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Line adalah satu pesan hasil parse _chat.txt.
+type Line struct {
+	Time    time.Time
+	Name    string // kosong untuk baris sistem
+	Text    string
+	File    string // nama lampiran di dalam ZIP
+	Media   bool   // ada media, walau file-nya tidak ikut diekspor
+	System  bool
+	Revoked bool
+	Edited  bool
+}
+
+// Header baris ekspor, contoh yang dikenali:
+//
+//	18/10/25 19.05 - Budi: halo                 (Android, Indonesia)
+//	[18/10/25 19.05.12] Budi: halo              (iPhone, Indonesia)
+//	10/18/25, 7:05 PM - Budi: hello             (Android, English US)
+//	[18/10/2025, 19:05:12] Budi: hello          (iPhone, English UK)
+var waHeader = regexp.MustCompile(`^\[?(\d{1,2})[/.\-](\d{1,2})[/.\-](\d{2,4}),?\s+(\d{1,2})[:.](\d{2})(?:[:.](\d{2}))?\s*([AaPp]\.?\s?[Mm]\.?)?\]?\s*(?:-\s+)?(.*)$`)
+
+var (
+	waAttachedTag  = regexp.MustCompile(`^<(?:attached|terlampir|lampiran):\s*(.+?)>$`)
+	waAttachedText = regexp.MustCompile(`^(.+?\.\w{2,5}) \((?:file attached|file terlampir)\)$`)
+	waOmitted      = map[string]bool{
+		"<media omitted>": true, "<media tidak disertakan>": true, "<media dihilangkan>": true,
+		"image omitted": true, "video omitted": true, "audio omitted": true, "sticker omitted": true,
+		"document omitted": true, "gambar tidak disertakan": true, "video tidak disertakan": true,
+		"audio tidak disertakan": true, "stiker tidak disertakan": true,
+	}
+	waDeleted = map[string]bool{
+		"this message was deleted": true, "you deleted this message": true,
+		"pesan ini telah dihapus": true, "anda menghapus pesan ini": true,
+	}
+	waEditedSuffixes = []string{"<this message was edited>", "<pesan ini diedit>"}
+
+	// waSystemName mengenali baris sistem yang kebetulan mengandung ": ",
+	// mis. `Budi changed the subject from "A" to "Rapat: Senin"`. Bagian
+	// sebelum ": " di baris seperti itu berisi tanda kutip atau kata kerja
+	// sistem di tengah kalimat, yang tidak ada di nama pengirim biasa.
+	waSystemName = regexp.MustCompile(`"|\s(?:changed|created|added|removed|deleted|pinned|turned|joined|mengubah|membuat|menambahkan|mengeluarkan|menghapus|menyematkan|mengaktifkan|menonaktifkan|bergabung)\s`)
+)
+
+// waCleaner membuang karakter tak terlihat yang disisipkan WhatsApp
+// (LRM, BOM) dan menormalkan spasi sempit sebelum AM/PM.
+var waCleaner = strings.NewReplacer("\u200e", "", "\u200f", "", "\ufeff", "", "\u202f", " ", "\u00a0", " ")
+
+type rawHeader struct {
+	a, b, year, hour, min, sec int
+	ampm                       string
+	rest                       string
+}
+
+// Parse membaca _chat.txt. dateOrder "dmy" atau "mdy"; kosong berarti
+// ditebak dari isi file (hari > 12), dengan dmy sebagai default.
+func Parse(r io.Reader, dateOrder string, loc *time.Location) ([]Line, string, error) {
+	type pending struct {
+		h    rawHeader
+		body []string
+	}
+	var items []*pending
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	guessDMY, guessMDY := false, false
+	for sc.Scan() {
+		line := waCleaner.Replace(sc.Text())
+		if m := waHeader.FindStringSubmatch(line); m != nil {
+			h := rawHeader{ampm: strings.ToLower(strings.NewReplacer(".", "", " ", "").Replace(m[7])), rest: m[8]}
+			h.a, _ = strconv.Atoi(m[1])
+			h.b, _ = strconv.Atoi(m[2])
+			h.year, _ = strconv.Atoi(m[3])
+			h.hour, _ = strconv.Atoi(m[4])
+			h.min, _ = strconv.Atoi(m[5])
+			h.sec, _ = strconv.Atoi(m[6])
+			if h.a > 12 {
+				guessDMY = true
+			}
+			if h.b > 12 {
+				guessMDY = true
+			}
+			items = append(items, &pending{h: h})
+			continue
+		}
+		if len(items) == 0 {
+			continue // teks sebelum pesan pertama (mis. header kosong)
+		}
+		last := items[len(items)-1]
+		last.body = append(last.body, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, "", err
+	}
+	if len(items) == 0 {
+		return nil, "", errors.New("no WhatsApp chat lines found")
+	}
+
+	if dateOrder == "" {
+		dateOrder = "dmy"
+		if guessMDY && !guessDMY {
+			dateOrder = "mdy"
+		}
+	}
+	if dateOrder != "dmy" && dateOrder != "mdy" {
+		return nil, "", errors.New("date_order must be dmy or mdy")
+	}
+
+	out := make([]Line, 0, len(items))
+	for _, it := range items {
+		h := it.h
+		day, month := h.a, h.b
+		if dateOrder == "mdy" {
+			day, month = h.b, h.a
+		}
+		if h.year < 100 {
+			h.year += 2000
+		}
+		switch {
+		case h.ampm == "pm" && h.hour < 12:
+			h.hour += 12
+		case h.ampm == "am" && h.hour == 12:
+			h.hour = 0
+		}
+		if month < 1 || month > 12 || day < 1 || day > 31 {
+			return nil, "", fmt.Errorf("invalid date %02d/%02d/%d; try date_order=%s", h.a, h.b, h.year,
+				map[string]string{"dmy": "mdy", "mdy": "dmy"}[dateOrder])
+		}
+		l := Line{Time: time.Date(h.year, time.Month(month), day, h.hour, h.min, h.sec, 0, loc)}
+
+		text := h.rest
+		if name, msg, ok := strings.Cut(h.rest, ": "); ok && len(name) <= 80 && !waSystemName.MatchString(name) {
+			l.Name, text = strings.TrimSpace(name), msg
+		} else {
+			l.System = true
+		}
+		if len(it.body) > 0 {
+			text += "\n" + strings.Join(it.body, "\n")
+		}
+		if !l.System {
+			classify(&l, text)
+		} else {
+			l.Text = text
+		}
+		out = append(out, l)
+	}
+	return out, dateOrder, nil
+}
+
+// classify mengenali lampiran, media yang tidak diekspor, pesan
+// terhapus dan penanda edit. Caption lampiran ada di baris berikutnya.
+func classify(l *Line, text string) {
+	first, rest, _ := strings.Cut(text, "\n")
+	first = strings.TrimSpace(first)
+	lower := strings.ToLower(first)
+	for _, suffix := range waEditedSuffixes {
+		if strings.HasSuffix(strings.ToLower(text), suffix) {
+			l.Edited = true
+			text = strings.TrimSpace(text[:len(text)-len(suffix)])
+			first, rest, _ = strings.Cut(text, "\n")
+			first = strings.TrimSpace(first)
+			lower = strings.ToLower(first)
+		}
+	}
+	switch {
+	case waAttachedTag.MatchString(first):
+		l.File = waAttachedTag.FindStringSubmatch(first)[1]
+		l.Media, l.Text = true, rest
+	case waAttachedText.MatchString(first):
+		l.File = waAttachedText.FindStringSubmatch(first)[1]
+		l.Media, l.Text = true, rest
+	case waOmitted[lower]:
+		l.Media, l.Text = true, rest
+	case waDeleted[lower] && rest == "":
+		l.Revoked = true
+	default:
+		l.Text = text
+	}
+}
+
+// AttachmentType menebak tipe pesan dan mimetype dari nama file lampiran.
+func AttachmentType(name string) (typ, mimetype string) {
+	ext := strings.ToLower(filepath.Ext(name))
+	mimetype = mime.TypeByExtension(ext)
+	upper := strings.ToUpper(name)
+	switch {
+	case strings.Contains(upper, "STICKER") || strings.HasPrefix(upper, "STK-"):
+		typ = "sticker"
+	case ext == ".opus" || ext == ".ogg" || ext == ".m4a" || ext == ".mp3" || ext == ".aac":
+		typ = "audio"
+		if ext == ".opus" {
+			mimetype = "audio/ogg; codecs=opus"
+		}
+	case strings.HasPrefix(mimetype, "image/"):
+		typ = "image"
+	case strings.HasPrefix(mimetype, "video/") || ext == ".3gp":
+		typ = "video"
+	default:
+		typ = "document"
+	}
+	if mimetype == "" {
+		mimetype = "application/octet-stream"
+	}
+	return typ, mimetype
+}
+
+// FindChatTxt mencari _chat.txt (iPhone) atau "WhatsApp Chat with X.txt" (Android).
+func FindChatTxt(zr *zip.Reader) *zip.File {
+	var found *zip.File
+	for _, f := range zr.File {
+		if strings.EqualFold(path.Base(f.Name), "_chat.txt") {
+			return f
+		}
+		if found == nil && strings.EqualFold(path.Ext(f.Name), ".txt") {
+			found = f
+		}
+	}
+	return found
+}
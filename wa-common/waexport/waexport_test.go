@@ -0,0 +1,149 @@
+package waexport
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	loc := time.FixedZone("WIB", 7*3600)
+	at := func(y int, mo time.Month, d, h, mi, s int) time.Time {
+		return time.Date(y, mo, d, h, mi, s, 0, loc)
+	}
+	tests := []struct {
+		name      string
+		input     string
+		dateOrder string
+		wantOrder string
+		want      []Line
+		wantErr   bool
+	}{
+		{
+			name:      "android indonesia",
+			input:     "18/10/25 19.05 - Budi: halo\n18/10/25 19.06 - Ani: hai juga",
+			wantOrder: "dmy",
+			want: []Line{
+				{Time: at(2025, 10, 18, 19, 5, 0), Name: "Budi", Text: "halo"},
+				{Time: at(2025, 10, 18, 19, 6, 0), Name: "Ani", Text: "hai juga"},
+			},
+		},
+		{
+			name:      "iphone dengan detik dan karakter tak terlihat",
+			input:     "\ufeff[18/10/25 19.05.12] Budi: \u200ehalo",
+			wantOrder: "dmy",
+			want:      []Line{{Time: at(2025, 10, 18, 19, 5, 12), Name: "Budi", Text: "halo"}},
+		},
+		{
+			name:      "english us am/pm ditebak mdy",
+			input:     "10/18/25, 7:05\u202fPM - Budi: hello\n10/19/25, 12:01 AM - Ani: hi",
+			wantOrder: "mdy",
+			want: []Line{
+				{Time: at(2025, 10, 18, 19, 5, 0), Name: "Budi", Text: "hello"},
+				{Time: at(2025, 10, 19, 0, 1, 0), Name: "Ani", Text: "hi"},
+			},
+		},
+		{
+			name:      "date_order eksplisit",
+			input:     "[01/02/2025, 09:00:00] Budi: pagi",
+			dateOrder: "mdy",
+			wantOrder: "mdy",
+			want:      []Line{{Time: at(2025, 1, 2, 9, 0, 0), Name: "Budi", Text: "pagi"}},
+		},
+		{
+			name:      "pesan multi-baris",
+			input:     "18/10/25 19.05 - Budi: baris satu\nbaris dua\n\nbaris empat",
+			wantOrder: "dmy",
+			want:      []Line{{Time: at(2025, 10, 18, 19, 5, 0), Name: "Budi", Text: "baris satu\nbaris dua\n\nbaris empat"}},
+		},
+		{
+			name: "lampiran, media, terhapus, diedit",
+			input: "18/10/25 19.05 - Budi: IMG-20251018-WA0001.jpg (file terlampir)\nini captionnya\n" +
+				"[18/10/25 19.06.00] Ani: <terlampir: 00000002-PHOTO-2025-10-18-19-06-00.jpg>\n" +
+				"18/10/25 19.07 - Budi: <Media tidak disertakan>\n" +
+				"18/10/25 19.08 - Ani: Pesan ini telah dihapus\n" +
+				"18/10/25 19.09 - Budi: jadi besok <Pesan ini diedit>",
+			wantOrder: "dmy",
+			want: []Line{
+				{Time: at(2025, 10, 18, 19, 5, 0), Name: "Budi", Text: "ini captionnya", File: "IMG-20251018-WA0001.jpg", Media: true},
+				{Time: at(2025, 10, 18, 19, 6, 0), Name: "Ani", File: "00000002-PHOTO-2025-10-18-19-06-00.jpg", Media: true},
+				{Time: at(2025, 10, 18, 19, 7, 0), Name: "Budi", Media: true},
+				{Time: at(2025, 10, 18, 19, 8, 0), Name: "Ani", Revoked: true},
+				{Time: at(2025, 10, 18, 19, 9, 0), Name: "Budi", Text: "jadi besok", Edited: true},
+			},
+		},
+		{
+			name: "baris sistem",
+			input: "18/10/25 19.00 - Pesan dan panggilan terenkripsi secara end-to-end.\n" +
+				"18/10/25 19.01 - Budi membuat grup \"Kantor: Tim A\"\n" +
+				"18/10/25 19.02 - Budi changed the subject from \"Kantor\" to \"Rapat: Senin\"\n" +
+				"18/10/25 19.03 - Budi mengubah deskripsi grup: jam 9\n" +
+				"18/10/25 19.04 - Budi: pesan biasa: dengan titik dua",
+			wantOrder: "dmy",
+			want: []Line{
+				{Time: at(2025, 10, 18, 19, 0, 0), System: true, Text: "Pesan dan panggilan terenkripsi secara end-to-end."},
+				{Time: at(2025, 10, 18, 19, 1, 0), System: true, Text: "Budi membuat grup \"Kantor: Tim A\""},
+				{Time: at(2025, 10, 18, 19, 2, 0), System: true, Text: "Budi changed the subject from \"Kantor\" to \"Rapat: Senin\""},
+				{Time: at(2025, 10, 18, 19, 3, 0), System: true, Text: "Budi mengubah deskripsi grup: jam 9"},
+				{Time: at(2025, 10, 18, 19, 4, 0), Name: "Budi", Text: "pesan biasa: dengan titik dua"},
+			},
+		},
+		{
+			name:      "teks sebelum pesan pertama diabaikan",
+			input:     "\n\n18/10/25 19.05 - Budi: halo",
+			wantOrder: "dmy",
+			want:      []Line{{Time: at(2025, 10, 18, 19, 5, 0), Name: "Budi", Text: "halo"}},
+		},
+		{name: "bukan ekspor whatsapp", input: "halo\napa kabar", wantErr: true},
+		{name: "date_order tidak dikenal", input: "18/10/25 19.05 - Budi: halo", dateOrder: "ymd", wantErr: true},
+		{name: "tanggal tidak valid untuk urutan", input: "18/10/25 19.05 - Budi: halo", dateOrder: "mdy", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, order, err := Parse(strings.NewReader(tt.input), tt.dateOrder, loc)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got %d lines", len(got))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if order != tt.wantOrder {
+				t.Errorf("date order = %q, want %q", order, tt.wantOrder)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d lines, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if !got[i].Time.Equal(tt.want[i].Time) {
+					t.Errorf("line %d: time = %s, want %s", i, got[i].Time, tt.want[i].Time)
+				}
+				g, w := got[i], tt.want[i]
+				g.Time, w.Time = time.Time{}, time.Time{}
+				if g != w {
+					t.Errorf("line %d:\n got %+v\nwant %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestAttachmentType(t *testing.T) {
+	tests := []struct{ name, typ, mimetype string }{
+		{"IMG-20251018-WA0001.jpg", "image", "image/jpeg"},
+		{"00000002-PHOTO-2025-10-18-19-06-00.jpg", "image", "image/jpeg"},
+		{"VID-20251018-WA0002.mp4", "video", "video/mp4"},
+		{"PTT-20251018-WA0003.opus", "audio", "audio/ogg; codecs=opus"},
+		{"STK-20251018-WA0004.webp", "sticker", "image/webp"},
+		{"laporan.pdf", "document", "application/pdf"},
+		{"data.unknownext", "document", "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		typ, mimetype := AttachmentType(tt.name)
+		if typ != tt.typ || mimetype != tt.mimetype {
+			t.Errorf("AttachmentType(%q) = %q, %q; want %q, %q", tt.name, typ, mimetype, tt.typ, tt.mimetype)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+/* ---------- Owner commands ---------- */
+
+// Perintah admin dikirim lewat chat pribadi ke nomor gateway dari nomor
+// yang terdaftar di COMMAND_OWNERS (atau "owners" di /commands/config).
+// Semuanya memanggil fungsi yang sama dengan route HTTP-nya:
+//
+//	!status                 GET /login, GET /webhook/queue
+//	!webhook list|add|remove <url>   /webhook
+//	!queue                  GET /webhook/queue
+//	!pause / !resume        POST /webhook/pause, POST /webhook/resume
+func init() {
+	for _, c := range []*command{
+		{Name: "status", Usage: "status", Description: "status gateway", Handler: statusCommand},
+		{Name: "webhook", Usage: "webhook list | add <url> | remove <url>", Description: "kelola webhook", Handler: webhookCommand},
+		{Name: "queue", Usage: "queue", Description: "isi antrean webhook", Handler: queueCommand},
+		{Name: "pause", Usage: "pause", Description: "jeda pengiriman webhook", Handler: pauseCommand},
+		{Name: "resume", Usage: "resume", Description: "lanjutkan webhook dan kirim antrean", Handler: resumeCommand},
+	} {
+		c.Permission, c.PrivateOnly = permOwner, true
+		registerCommand(c)
+	}
+}
+
+func statusCommand(c *commandCall) (string, error) {
+	s := sessionStatus()
+	var b strings.Builder
+	if cli.IsConnected() {
+		fmt.Fprintf(&b, "🟢 Terhubung sebagai %s\n", s.LoggedInAs)
+	} else {
+		fmt.Fprintf(&b, "🔴 Tidak terhubung (%s)\n", s.Status)
+	}
+	fmt.Fprintf(&b, "⏱️ Uptime: %s\n", time.Since(startTime).Round(time.Second))
+	q := webhookQueue()
+	fmt.Fprintf(&b, "🔗 Webhook: %d", len(listWebhooks()))
+	switch {
+	case q.Paused:
+		fmt.Fprintf(&b, " (dijeda, %d antre)", q.Queued)
+	case q.Resuming:
+		fmt.Fprintf(&b, " (mengirim antrean, %d tersisa)", q.Queued)
+	}
+	b.WriteString("\n")
+	if archiveEnabled {
+		b.WriteString("🗄️ Arsip pesan: aktif")
+	} else {
+		b.WriteString("🗄️ Arsip pesan: nonaktif")
+	}
+	return b.String(), nil
+}
+
+func webhookCommand(c *commandCall) (string, error) {
+	sub := "list"
+	if len(c.Args) > 0 {
+		sub = strings.ToLower(c.Args[0])
+	}
+	switch sub {
+	case "list":
+		urls := listWebhooks()
+		if len(urls) == 0 {
+			return "Belum ada webhook.", nil
+		}
+		var b strings.Builder
+		b.WriteString("🔗 Webhook:")
+		for i, u := range urls {
+			fmt.Fprintf(&b, "\n%d. %s", i+1, u)
+		}
+		return b.String(), nil
+	case "add", "remove", "rm", "del":
+		if len(c.Args) < 2 {
+			return "", fmt.Errorf("pakai: webhook %s <url>", sub)
+		}
+		u := c.Args[1]
+		if sub == "add" {
+			if parsed, err := url.ParseRequestURI(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+				return "", fmt.Errorf("url tidak valid: %s", u)
+			}
+			return fmt.Sprintf("✅ Ditambahkan. Total webhook: %d", addWebhook(u)), nil
+		}
+		before := len(listWebhooks())
+		total := removeWebhook(u)
+		if total == before {
+			return "", fmt.Errorf("webhook tidak ditemukan: %s", u)
+		}
+		return fmt.Sprintf("✅ Dihapus. Total webhook: %d", total), nil
+	}
+	return "", fmt.Errorf("subperintah tidak dikenal: %s (list, add, remove)", sub)
+}
+
+func queueCommand(c *commandCall) (string, error) {
+	q := webhookQueue()
+	if q.Resuming {
+		return fmt.Sprintf("⏩ Mengirim antrean: %d terkirim, %d gagal, %d tersisa.", q.Sent, q.Failed, q.Queued), nil
+	}
+	if !q.Paused {
+		return "▶️ Webhook berjalan, antrean kosong.", nil
+	}
+	msg := fmt.Sprintf("⏸️ Dijeda sejak %s, %d payload antre (maks %d)",
+		q.PausedAt.Format("02/01 15.04"), q.Queued, q.Max)
+	if q.Dropped > 0 {
+		msg += fmt.Sprintf(", %d terbuang karena penuh", q.Dropped)
+	}
+	return msg + ".", nil
+}
+
+func pauseCommand(c *commandCall) (string, error) {
+	pauseWebhooks()
+	return "⏸️ Webhook dijeda. Pesan ditampung sampai !resume.", nil
+}
+
+func resumeCommand(c *commandCall) (string, error) {
+	q := resumeWebhooks()
+	if q.Resuming && q.Queued > 0 {
+		return fmt.Sprintf("▶️ Webhook jalan lagi. %d payload antre sedang dikirim, cek dengan !queue.", q.Queued), nil
+	}
+	return "▶️ Webhook jalan lagi.", nil
+}
@@ -0,0 +1,511 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+	"wa-common/phone"
+)
+
+/* ---------- Message archive ---------- */
+
+// Arsip pesan bersifat opsional (MESSAGE_ARCHIVE=1) dan disimpan di
+// gateway.db, terpisah dari tabel session whatsmeow. Pencarian memakai
+// SQLite FTS5 kalau driver dikompilasi dengan -tags sqlite_fts5; kalau tidak,
+// pencarian jatuh ke LIKE biasa.
+var (
+	archiveEnabled = envBool("MESSAGE_ARCHIVE", false)
+	archiveFTS     bool
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+var archiveSchema = []string{
+	`CREATE TABLE IF NOT EXISTS messages (
+		chat      TEXT NOT NULL,
+		id        TEXT NOT NULL,
+		sender    TEXT NOT NULL,
+		push_name TEXT NOT NULL DEFAULT '',
+		from_me   INTEGER NOT NULL,
+		time      INTEGER NOT NULL,
+		type      TEXT NOT NULL,
+		text      TEXT NOT NULL DEFAULT '',
+		quoted_id TEXT NOT NULL DEFAULT '',
+		mimetype  TEXT NOT NULL DEFAULT '',
+		media     BLOB,
+		edited    INTEGER NOT NULL DEFAULT 0,
+		revoked   INTEGER NOT NULL DEFAULT 0,
+		reactions TEXT NOT NULL DEFAULT '{}',
+		source    TEXT NOT NULL DEFAULT 'live',
+		PRIMARY KEY (chat, id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS messages_chat_time ON messages (chat, time)`,
+	`CREATE TABLE IF NOT EXISTS chats (
+		jid       TEXT PRIMARY KEY,
+		name      TEXT NOT NULL DEFAULT '',
+		is_group  INTEGER NOT NULL,
+		last_time INTEGER NOT NULL
+	)`,
+}
+
+// archiveMigrations menambah kolom ke arsip lama; error "duplicate column"
+// berarti migrasi sudah pernah jalan.
+var archiveMigrations = []string{
+	`ALTER TABLE messages ADD COLUMN file TEXT NOT NULL DEFAULT ''`, // lampiran lokal dari import ekspor WhatsApp
+}
+
+var archiveFTSSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(text, content='messages', content_rowid='rowid')`,
+	`CREATE TRIGGER IF NOT EXISTS messages_fts_ai AFTER INSERT ON messages BEGIN
+		INSERT INTO messages_fts (rowid, text) VALUES (new.rowid, new.text);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS messages_fts_ad AFTER DELETE ON messages BEGIN
+		INSERT INTO messages_fts (messages_fts, rowid, text) VALUES ('delete', old.rowid, old.text);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS messages_fts_au AFTER UPDATE OF text ON messages BEGIN
+		INSERT INTO messages_fts (messages_fts, rowid, text) VALUES ('delete', old.rowid, old.text);
+		INSERT INTO messages_fts (rowid, text) VALUES (new.rowid, new.text);
+	END`,
+}
+
+// archivedMessage adalah satu baris arsip seperti yang dikembalikan API.
+type archivedMessage struct {
+	msgEvent
+	Mimetype  string            `json:"mimetype,omitempty"`
+	MediaURL  string            `json:"media_url,omitempty"`
+	Revoked   bool              `json:"revoked,omitempty"`
+	Reactions map[string]string `json:"reactions,omitempty"` // sender -> emoji
+	Source    string            `json:"source"`
+}
+
+type chatSummary struct {
+	JID      string    `json:"jid"`
+	Name     string    `json:"name,omitempty"`
+	IsGroup  bool      `json:"is_group"`
+	LastTime time.Time `json:"last_time"`
+	LastText string    `json:"last_text,omitempty"`
+	Messages int       `json:"messages"`
+}
+
+// openArchive dipanggil setelah openGatewayDB. FTS5 dipakai kalau driver
+// dikompilasi dengannya. Kalau tidak, trigger FTS dari build sebelumnya
+// dihapus supaya INSERT ke messages tidak gagal ("no such module: fts5");
+// index dibangun ulang saat gateway kembali jalan dengan FTS5.
+func openArchive() error {
+	if !archiveEnabled {
+		return nil
+	}
+	for _, stmt := range archiveSchema {
+		if _, err := gwDB.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	for _, stmt := range archiveMigrations {
+		if _, err := gwDB.Exec(stmt); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			return err
+		}
+	}
+
+	if err := gwDB.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&archiveFTS); err != nil {
+		return err
+	}
+	if !archiveFTS {
+		for _, trigger := range []string{"messages_fts_ai", "messages_fts_ad", "messages_fts_au"} {
+			if _, err := gwDB.Exec(`DROP TRIGGER IF EXISTS ` + trigger); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// trigger belum ada: arsip baru, atau pernah jalan tanpa FTS5 sehingga
+	// index ketinggalan dan perlu dibangun ulang dari tabel messages
+	var hadTrigger bool
+	if err := gwDB.QueryRow(`SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'trigger' AND name = 'messages_fts_ai')`).Scan(&hadTrigger); err != nil {
+		return err
+	}
+	for _, stmt := range archiveFTSSchema {
+		if _, err := gwDB.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if !hadTrigger {
+		if _, err := gwDB.Exec(`INSERT INTO messages_fts (messages_fts) VALUES ('rebuild')`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func registerArchiveRoutes() {
+	http.HandleFunc("GET /chats", listChatsHandler)
+	http.HandleFunc("GET /chats/{jid}/messages", chatMessagesHandler)
+	http.HandleFunc("GET /chats/{jid}/messages/{id}/media", messageMediaHandler)
+	http.HandleFunc("GET /messages/search", searchMessagesHandler)
+}
+
+// sendMessage membungkus cli.SendMessage supaya pesan keluar lewat API ikut
+// masuk arsip.
+func sendMessage(ctx context.Context, to types.JID, msg *waProto.Message) (whatsmeow.SendResponse, error) {
+	resp, err := cli.SendMessage(ctx, to, msg)
+	if err == nil && archiveEnabled {
+		chat, _ := phoneJID(to, types.EmptyJID)
+		e := msgEvent{
+			ID:     resp.ID,
+			Chat:   to.String(),
+			FromMe: true,
+			Time:   resp.Timestamp,
+			Type:   messageType(msg),
+			Text:   messageText(msg),
+
+			archiveChat: chat.String(),
+		}
+		if cli.Store.ID != nil {
+			e.Sender = cli.Store.ID.ToNonAD().String()
+			e.Phone = cli.Store.ID.User
+		}
+		e.IsGroup = to.Server == types.GroupServer
+		archiveMessage(e, msg, "api")
+	}
+	return resp, err
+}
+
+// execer dipenuhi *sql.DB maupun *sql.Tx, supaya import massal (history
+// sync) bisa memakai satu transaksi.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// archiveMessage menyimpan satu pesan. Pesan yang sudah ada (mis. dari history
+// sync yang diulang) tidak ditimpa. Dipanggil dari event handler, jadi error
+// hanya dicatat ke log.
+func archiveMessage(e msgEvent, m *waProto.Message, source string) {
+	if !archiveEnabled {
+		return
+	}
+	if _, err := archiveInsert(gwDB, e, m, source); err != nil {
+		log.Printf("archive: simpan pesan %s di %s: %v", e.ID, e.Chat, err)
+	}
+}
+
+// archiveInsert mengembalikan true kalau pesan baru benar-benar ditambahkan.
+// Chat dan pengirim disimpan dengan JID nomor telepon kalau diketahui,
+// supaya pesan masuk lewat @lid dan balasan kita ke nomornya ada di satu chat.
+func archiveInsert(db execer, e msgEvent, m *waProto.Message, source string) (bool, error) {
+	chat, sender := e.archiveChat, e.Sender
+	if chat == "" {
+		chat = e.Chat
+	}
+	if e.Phone != "" {
+		sender = types.NewJID(e.Phone, types.DefaultUserServer).String()
+	}
+	var media []byte
+	mimetype := mediaMimetype(m)
+	if mimetype != "" {
+		media, _ = proto.Marshal(m)
+	}
+	res, err := db.Exec(`INSERT OR IGNORE INTO messages
+		(chat, id, sender, push_name, from_me, time, type, text, quoted_id, mimetype, media, edited, source)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		chat, e.ID, sender, e.PushName, e.FromMe, e.Time.Unix(), e.Type, e.Text, e.QuotedID, mimetype, media, e.Edited, source)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	name := ""
+	if !e.IsGroup && !e.FromMe {
+		name = e.PushName
+	}
+	return true, touchChat(db, chat, name, e.IsGroup, e.Time)
+}
+
+// touchChat memperbarui daftar chat; nama kosong tidak menimpa nama lama.
+func touchChat(db execer, jid, name string, isGroup bool, last time.Time) error {
+	_, err := db.Exec(`INSERT INTO chats (jid, name, is_group, last_time) VALUES (?, ?, ?, ?)
+		ON CONFLICT (jid) DO UPDATE SET
+			name = CASE WHEN excluded.name != '' THEN excluded.name ELSE chats.name END,
+			last_time = MAX(chats.last_time, excluded.last_time)`,
+		jid, name, isGroup, last.Unix())
+	return err
+}
+
+// archiveUpdate menerapkan edit, revoke dan reaksi ke salinan di arsip,
+// dengan kunci chat dan pengirim yang sama seperti archiveInsert.
+func archiveUpdate(typ string, upd messageUpdate) {
+	if !archiveEnabled {
+		return
+	}
+	chat, sender := upd.archiveChat, upd.archiveSender
+	var err error
+	switch typ {
+	case "message.edit":
+		_, err = gwDB.Exec(`UPDATE messages SET text = ?, edited = 1 WHERE chat = ? AND id = ?`, upd.Text, chat, upd.TargetID)
+	case "message.revoke":
+		_, err = gwDB.Exec(`UPDATE messages SET text = '', media = NULL, revoked = 1 WHERE chat = ? AND id = ?`, chat, upd.TargetID)
+	case "message.reaction":
+		if upd.Removed {
+			_, err = gwDB.Exec(`UPDATE messages SET reactions = json_remove(reactions, '$."' || ? || '"') WHERE chat = ? AND id = ?`,
+				sender, chat, upd.TargetID)
+		} else {
+			_, err = gwDB.Exec(`UPDATE messages SET reactions = json_set(reactions, '$."' || ? || '"', ?) WHERE chat = ? AND id = ?`,
+				sender, upd.Emoji, chat, upd.TargetID)
+		}
+	}
+	if err != nil {
+		log.Printf("archive: %s %s di %s: %v", typ, upd.TargetID, chat, err)
+	}
+}
+
+func mediaMimetype(m *waProto.Message) string {
+	switch {
+	case m.GetImageMessage() != nil:
+		return m.GetImageMessage().GetMimetype()
+	case m.GetVideoMessage() != nil:
+		return m.GetVideoMessage().GetMimetype()
+	case m.GetAudioMessage() != nil:
+		return m.GetAudioMessage().GetMimetype()
+	case m.GetDocumentMessage() != nil:
+		return m.GetDocumentMessage().GetMimetype()
+	case m.GetStickerMessage() != nil:
+		return m.GetStickerMessage().GetMimetype()
+	}
+	return ""
+}
+
+/* ---------- Archive API ---------- */
+
+func requireArchive(w http.ResponseWriter) bool {
+	if !archiveEnabled {
+		writeError(w, 404, "message archive disabled (set MESSAGE_ARCHIVE=1)")
+		return false
+	}
+	return true
+}
+
+func listChatsHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireArchive(w) {
+		return
+	}
+	rows, err := gwDB.Query(`SELECT c.jid, c.name, c.is_group, c.last_time,
+			(SELECT text FROM messages m WHERE m.chat = c.jid ORDER BY time DESC, rowid DESC LIMIT 1),
+			(SELECT COUNT(*) FROM messages m WHERE m.chat = c.jid)
+		FROM chats c ORDER BY c.last_time DESC`)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	defer rows.Close()
+	out := []chatSummary{}
+	for rows.Next() {
+		var c chatSummary
+		var last int64
+		var text sql.NullString
+		if err := rows.Scan(&c.JID, &c.Name, &c.IsGroup, &last, &text, &c.Messages); err != nil {
+			writeError(w, 500, err.Error())
+			return
+		}
+		c.LastTime, c.LastText = time.Unix(last, 0), text.String
+		out = append(out, c)
+	}
+	writeJSON(w, out)
+}
+
+// chatMessagesHandler: GET /chats/{jid}/messages?before=<id|unix>&limit=50.
+// Hasil urut dari yang terbaru; pakai next_before untuk halaman berikutnya.
+func chatMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireArchive(w) {
+		return
+	}
+	jid, err := phone.NormalizeJID(r.PathValue("jid"))
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	limit := pageLimit(r)
+	query := `SELECT ` + messageColumns + ` FROM messages WHERE chat = ?`
+	args := []interface{}{jid.String()}
+
+	if before := r.URL.Query().Get("before"); before != "" {
+		if ts, err := strconv.ParseInt(before, 10, 64); err == nil && len(before) <= 11 {
+			query += ` AND time < ?`
+			args = append(args, ts)
+		} else {
+			var ts, rowid int64
+			err := gwDB.QueryRow(`SELECT time, rowid FROM messages WHERE chat = ? AND id = ?`, jid.String(), before).Scan(&ts, &rowid)
+			if errors.Is(err, sql.ErrNoRows) {
+				writeError(w, 400, "before must be a message id from this chat or a unix timestamp")
+				return
+			} else if err != nil {
+				writeError(w, 500, err.Error())
+				return
+			}
+			query += ` AND (time, rowid) < (?, ?)`
+			args = append(args, ts, rowid)
+		}
+	}
+	query += ` ORDER BY time DESC, rowid DESC LIMIT ?`
+	args = append(args, limit)
+
+	msgs, err := queryMessages(query, args...)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	resp := map[string]interface{}{"messages": msgs}
+	if len(msgs) == limit {
+		resp["next_before"] = msgs[len(msgs)-1].ID
+	}
+	writeJSON(w, resp)
+}
+
+// searchMessagesHandler: GET /messages/search?q=...&chat=...&limit=50.
+func searchMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireArchive(w) {
+		return
+	}
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeError(w, 400, "q is required")
+		return
+	}
+	var query string
+	var args []interface{}
+	if archiveFTS {
+		query = `SELECT ` + messageColumns + ` FROM messages WHERE rowid IN (SELECT rowid FROM messages_fts WHERE messages_fts MATCH ?)`
+		args = append(args, ftsQuery(q))
+	} else {
+		query = `SELECT ` + messageColumns + ` FROM messages WHERE text LIKE ?`
+		args = append(args, "%"+q+"%")
+	}
+	if c := r.URL.Query().Get("chat"); c != "" {
+		jid, err := phone.NormalizeJID(c)
+		if err != nil {
+			writeError(w, 400, err.Error())
+			return
+		}
+		query += ` AND chat = ?`
+		args = append(args, jid.String())
+	}
+	query += ` ORDER BY time DESC LIMIT ?`
+	args = append(args, pageLimit(r))
+
+	msgs, err := queryMessages(query, args...)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]interface{}{"messages": msgs, "fts": archiveFTS})
+}
+
+// ftsQuery mengutip tiap kata supaya input pengguna (tanda kutip, titik dua,
+// operator) tidak dibaca sebagai sintaks FTS5. Kata terakhir dicari sebagai prefix.
+func ftsQuery(q string) string {
+	words := strings.Fields(q)
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+	}
+	words[len(words)-1] += "*"
+	return strings.Join(words, " ")
+}
+
+// messageMediaHandler mengirim lampiran lokal (hasil import) apa adanya, dan
+// mendownload media WhatsApp untuk pesan lain.
+func messageMediaHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireArchive(w) {
+		return
+	}
+	jid, err := phone.NormalizeJID(r.PathValue("jid"))
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	var raw []byte
+	var mimetype, file string
+	err = gwDB.QueryRow(`SELECT media, mimetype, file FROM messages WHERE chat = ? AND id = ? AND (media IS NOT NULL OR file != '')`,
+		jid.String(), r.PathValue("id")).Scan(&raw, &mimetype, &file)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, 404, "media not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	if file != "" {
+		w.Header().Set("Content-Type", mimetype)
+		http.ServeFile(w, r, file)
+		return
+	}
+	if !requireLogin(w) {
+		return
+	}
+	var msg waProto.Message
+	if err := proto.Unmarshal(raw, &msg); err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	data, err := cli.DownloadAny(r.Context(), &msg)
+	if err != nil {
+		writeError(w, 502, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", mimetype)
+	_, _ = w.Write(data)
+}
+
+const messageColumns = `chat, id, sender, push_name, from_me, time, type, text, quoted_id, mimetype, media IS NOT NULL OR file != '', edited, revoked, reactions, source`
+
+func queryMessages(query string, args ...interface{}) ([]archivedMessage, error) {
+	rows, err := gwDB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []archivedMessage{}
+	for rows.Next() {
+		var m archivedMessage
+		var ts int64
+		var hasMedia bool
+		var reactions string
+		if err := rows.Scan(&m.Chat, &m.ID, &m.Sender, &m.PushName, &m.FromMe, &ts, &m.Type, &m.Text,
+			&m.QuotedID, &m.Mimetype, &hasMedia, &m.Edited, &m.Revoked, &reactions, &m.Source); err != nil {
+			return nil, err
+		}
+		m.Time = time.Unix(ts, 0)
+		m.IsGroup = strings.HasSuffix(m.Chat, "@"+types.GroupServer)
+		if hasMedia {
+			m.MediaURL = "/chats/" + m.Chat + "/messages/" + m.ID + "/media"
+		}
+		_ = json.Unmarshal([]byte(reactions), &m.Reactions)
+		if len(m.Reactions) == 0 {
+			m.Reactions = nil
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func pageLimit(r *http.Request) int {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		return defaultPageSize
+	}
+	if limit > maxPageSize {
+		return maxPageSize
+	}
+	return limit
+}
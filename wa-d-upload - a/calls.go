@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+	"wa-common/phone"
+)
+
+/* ---------- Calls ---------- */
+
+// callPolicy menentukan apa yang dilakukan gateway saat ada telepon masuk.
+// Nilai awal diambil dari env, lalu bisa diubah lewat PUT /calls/policy dan
+// disimpan di gateway.db supaya tetap berlaku setelah restart. Begitu sudah
+// tersimpan, env di bawah tidak dibaca lagi; ubah lewat PUT /calls/policy.
+// Nomor di CALL_ALLOWLIST yang tidak valid dilewati (dicatat di log).
+//
+//	AUTO_REJECT_CALLS=1
+//	CALL_REJECT_MESSAGE="Maaf, nomor ini tidak menerima telepon. Silakan kirim pesan."
+//	CALL_ALLOWLIST=628111,628222   nomor yang teleponnya tidak ditolak
+//	REJECT_GROUP_CALLS=1           ikut tolak panggilan grup (balasan teks tidak dikirim)
+type callPolicy struct {
+	AutoReject  bool     `json:"auto_reject"`
+	Message     string   `json:"message"`
+	Allowlist   []string `json:"allowlist"`
+	RejectGroup bool     `json:"reject_group_calls"`
+}
+
+const callPolicyKey = "call_policy"
+
+// callReplyCooldown mencegah balasan otomatis dikirim berkali-kali ke
+// penelepon yang mencoba menelepon ulang.
+const callReplyCooldown = 10 * time.Minute
+
+var (
+	callMu      sync.RWMutex
+	calls       callPolicy
+	allowedCall map[string]bool
+	callReplied = newTTLCache(callReplyCooldown)
+)
+
+func registerCallRoutes() {
+	http.HandleFunc("GET /calls/policy", getCallPolicyHandler)
+	http.HandleFunc("PUT /calls/policy", putCallPolicyHandler)
+}
+
+// loadCallPolicy dipanggil sekali setelah gateway.db dibuka.
+func loadCallPolicy() error {
+	p := callPolicy{
+		AutoReject:  envBool("AUTO_REJECT_CALLS", false),
+		Message:     os.Getenv("CALL_REJECT_MESSAGE"),
+		RejectGroup: envBool("REJECT_GROUP_CALLS", false),
+		Allowlist:   envPhones("CALL_ALLOWLIST"),
+	}
+	found, err := loadSetting(callPolicyKey, &p)
+	if err != nil {
+		return err
+	}
+	if found && envSet("AUTO_REJECT_CALLS", "CALL_REJECT_MESSAGE", "CALL_ALLOWLIST", "REJECT_GROUP_CALLS") {
+		log.Printf("calls: env AUTO_REJECT_CALLS/CALL_* diabaikan, memakai kebijakan tersimpan di gateway.db")
+	}
+	return setCallPolicy(p)
+}
+
+func setCallPolicy(p callPolicy) error {
+	allowed := make(map[string]bool, len(p.Allowlist))
+	normalized := make([]string, 0, len(p.Allowlist))
+	for _, raw := range p.Allowlist {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		jid, err := phone.NormalizeJID(raw)
+		if err != nil {
+			return err
+		}
+		allowed[jid.User] = true
+		normalized = append(normalized, jid.User)
+	}
+	p.Allowlist = normalized
+	callMu.Lock()
+	calls, allowedCall = p, allowed
+	callMu.Unlock()
+	return nil
+}
+
+func getCallPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	callMu.RLock()
+	p := calls
+	callMu.RUnlock()
+	writeJSON(w, p)
+}
+
+// putCallPolicyHandler menerima {"auto_reject":true,"message":"...","allowlist":["0812..."]}.
+func putCallPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	var p callPolicy
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		writeError(w, 400, "bad json")
+		return
+	}
+	if err := setCallPolicy(p); err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	callMu.RLock()
+	p = calls
+	callMu.RUnlock()
+	if err := saveSetting(callPolicyKey, p); err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, p)
+}
+
+/* ---------- Call events ---------- */
+
+type callEvent struct {
+	CallID       string `json:"call_id"`
+	From         string `json:"from"`
+	Creator      string `json:"creator,omitempty"`
+	Group        string `json:"group,omitempty"`
+	Media        string `json:"media,omitempty"` // audio / video
+	Platform     string `json:"platform,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	AutoRejected bool   `json:"auto_rejected,omitempty"`
+	RejectError  string `json:"reject_error,omitempty"`
+}
+
+func newCallEvent(meta types.BasicCallMeta) callEvent {
+	evt := callEvent{CallID: meta.CallID, From: meta.From.ToNonAD().String()}
+	if !meta.CallCreator.IsEmpty() {
+		evt.Creator = meta.CallCreator.ToNonAD().String()
+	}
+	if !meta.GroupJID.IsEmpty() {
+		evt.Group = meta.GroupJID.String()
+	}
+	return evt
+}
+
+// handleCall mengirim event call.offer, call.accept, call.terminate dan
+// call.reject, dan menolak telepon masuk sesuai callPolicy.
+func handleCall(raw interface{}) {
+	switch v := raw.(type) {
+	case *events.CallOffer:
+		evt := newCallEvent(v.BasicCallMeta)
+		evt.Platform = v.RemotePlatform
+		evt.Media = "audio"
+		if _, ok := v.Data.GetOptionalChildByTag("video"); ok {
+			evt.Media = "video"
+		}
+		rejectCall(v.BasicCallMeta, false, &evt)
+		emit("call.offer", v.Timestamp, evt)
+	case *events.CallOfferNotice:
+		evt := newCallEvent(v.BasicCallMeta)
+		evt.Media = v.Media
+		rejectCall(v.BasicCallMeta, v.Type == "group", &evt)
+		emit("call.offer", v.Timestamp, evt)
+	case *events.CallAccept:
+		emit("call.accept", v.Timestamp, newCallEvent(v.BasicCallMeta))
+	case *events.CallTerminate:
+		evt := newCallEvent(v.BasicCallMeta)
+		evt.Reason = v.Reason
+		emit("call.terminate", v.Timestamp, evt)
+	case *events.CallReject:
+		emit("call.reject", v.Timestamp, newCallEvent(v.BasicCallMeta))
+	}
+}
+
+func rejectCall(meta types.BasicCallMeta, group bool, evt *callEvent) {
+	callMu.RLock()
+	p, allowed := calls, allowedCall
+	callMu.RUnlock()
+
+	caller := meta.CallCreator
+	if caller.IsEmpty() {
+		caller = meta.From
+	}
+	// allowlist berisi nomor, jadi penelepon @lid diterjemahkan dulu
+	caller, _ = phoneJID(caller, types.EmptyJID)
+	if !p.AutoReject || (group && !p.RejectGroup) || allowed[caller.User] {
+		return
+	}
+	if err := cli.RejectCall(meta.From, meta.CallID); err != nil {
+		evt.RejectError = err.Error()
+		return
+	}
+	evt.AutoRejected = true
+
+	if p.Message == "" || group {
+		return
+	}
+	if _, done := callReplied.get(caller.User); done {
+		return
+	}
+	callReplied.set(caller.User, true)
+	go func() {
+		_, _ = sendMessage(context.Background(), caller, &waProto.Message{Conversation: proto.String(p.Message)})
+	}()
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.mau.fi/whatsmeow/appstate"
+	"go.mau.fi/whatsmeow/proto/waSyncAction"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+	"wa-common/phone"
+)
+
+/* ---------- Chat management (app state) ---------- */
+
+// Semua aksi di sini dikirim sebagai patch app state, jadi ikut tersinkron
+// ke HP dan WhatsApp Web yang login di akun yang sama.
+func registerChatRoutes() {
+	http.HandleFunc("POST /chats/{jid}/archive", chatAction(func(jid types.JID, _ chatActionBody) appstate.PatchInfo {
+		return appstate.BuildArchive(jid, true, time.Time{}, nil)
+	}))
+	http.HandleFunc("POST /chats/{jid}/unarchive", chatAction(func(jid types.JID, _ chatActionBody) appstate.PatchInfo {
+		return appstate.BuildArchive(jid, false, time.Time{}, nil)
+	}))
+	http.HandleFunc("POST /chats/{jid}/pin", chatAction(func(jid types.JID, _ chatActionBody) appstate.PatchInfo {
+		return appstate.BuildPin(jid, true)
+	}))
+	http.HandleFunc("POST /chats/{jid}/unpin", chatAction(func(jid types.JID, _ chatActionBody) appstate.PatchInfo {
+		return appstate.BuildPin(jid, false)
+	}))
+	http.HandleFunc("POST /chats/{jid}/mute", chatAction(func(jid types.JID, b chatActionBody) appstate.PatchInfo {
+		return appstate.BuildMute(jid, true, b.duration)
+	}))
+	http.HandleFunc("POST /chats/{jid}/unmute", chatAction(func(jid types.JID, _ chatActionBody) appstate.PatchInfo {
+		return appstate.BuildMute(jid, false, 0)
+	}))
+	http.HandleFunc("POST /chats/{jid}/unread", chatAction(func(jid types.JID, _ chatActionBody) appstate.PatchInfo {
+		return buildMarkChatAsRead(jid, false)
+	}))
+	http.HandleFunc("POST /chats/{jid}/clear", chatAction(func(jid types.JID, b chatActionBody) appstate.PatchInfo {
+		forgetChat(jid)
+		return buildClearChat(jid, b.KeepStarred)
+	}))
+	http.HandleFunc("DELETE /chats/{jid}", chatAction(func(jid types.JID, _ chatActionBody) appstate.PatchInfo {
+		forgetChat(jid)
+		return buildDeleteChat(jid)
+	}))
+}
+
+// chatActionBody adalah body opsional untuk aksi chat:
+//
+//	{"duration":"8h"}      untuk mute; kosong berarti selamanya
+//	{"keep_starred":true}  untuk clear
+type chatActionBody struct {
+	Duration    string `json:"duration"`
+	KeepStarred bool   `json:"keep_starred"`
+
+	duration time.Duration
+}
+
+func chatAction(build func(types.JID, chatActionBody) appstate.PatchInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireLogin(w) {
+			return
+		}
+		jid, err := phone.NormalizeJID(r.PathValue("jid"))
+		if err != nil {
+			writeError(w, 400, err.Error())
+			return
+		}
+		var body chatActionBody
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeError(w, 400, "bad json")
+				return
+			}
+		}
+		if body.Duration != "" {
+			if body.duration, err = time.ParseDuration(body.Duration); err != nil || body.duration < 0 {
+				writeError(w, 400, "duration must look like 30m, 8h or 168h")
+				return
+			}
+		}
+		if err := cli.SendAppState(r.Context(), build(jid, body)); err != nil {
+			writeError(w, 500, err.Error())
+			return
+		}
+		writeJSON(w, map[string]string{"status": "ok", "chat": jid.String()})
+	}
+}
+
+// forgetChat membuang pesan yang dilacak tracker unread untuk chat yang dihapus/dikosongkan.
+func forgetChat(jid types.JID) {
+	unreadMu.Lock()
+	delete(unread, jid)
+	unreadMu.Unlock()
+}
+
+// whatsmeow belum punya builder untuk tiga patch di bawah; index dan versi
+// mengikuti yang dikirim WhatsApp Web.
+
+func buildMarkChatAsRead(jid types.JID, read bool) appstate.PatchInfo {
+	return appstate.PatchInfo{
+		Type: appstate.WAPatchRegularLow,
+		Mutations: []appstate.MutationInfo{{
+			Index:   []string{appstate.IndexMarkChatAsRead, jid.String()},
+			Version: 3,
+			Value: &waSyncAction.SyncActionValue{
+				MarkChatAsReadAction: &waSyncAction.MarkChatAsReadAction{
+					Read:         proto.Bool(read),
+					MessageRange: nowRange(),
+				},
+			},
+		}},
+	}
+}
+
+func buildClearChat(jid types.JID, keepStarred bool) appstate.PatchInfo {
+	deleteStarred := "1"
+	if keepStarred {
+		deleteStarred = "0"
+	}
+	return appstate.PatchInfo{
+		Type: appstate.WAPatchRegularHigh,
+		Mutations: []appstate.MutationInfo{{
+			Index:   []string{appstate.IndexClearChat, jid.String(), deleteStarred, "0"},
+			Version: 6,
+			Value: &waSyncAction.SyncActionValue{
+				ClearChatAction: &waSyncAction.ClearChatAction{MessageRange: nowRange()},
+			},
+		}},
+	}
+}
+
+func buildDeleteChat(jid types.JID) appstate.PatchInfo {
+	return appstate.PatchInfo{
+		Type: appstate.WAPatchRegularHigh,
+		Mutations: []appstate.MutationInfo{{
+			Index:   []string{appstate.IndexDeleteChat, jid.String(), "1"},
+			Version: 6,
+			Value: &waSyncAction.SyncActionValue{
+				DeleteChatAction: &waSyncAction.DeleteChatAction{MessageRange: nowRange()},
+			},
+		}},
+	}
+}
+
+func nowRange() *waSyncAction.SyncActionMessageRange {
+	return &waSyncAction.SyncActionMessageRange{LastMessageTimestamp: proto.Int64(time.Now().Unix())}
+}
+
+/* ---------- Chat events ---------- */
+
+type chatStateEvent struct {
+	Chat  string     `json:"chat"`
+	Value bool       `json:"value"`
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// handleAppState meneruskan perubahan dari HP/WhatsApp Web (chat.archive,
+// chat.pin, chat.mute, chat.read, chat.clear, chat.delete). Event dari full
+// sync awal dilewati karena itu bukan perubahan baru.
+func handleAppState(raw interface{}) {
+	switch v := raw.(type) {
+	case *events.Archive:
+		if !v.FromFullSync {
+			emit("chat.archive", v.Timestamp, chatStateEvent{Chat: v.JID.String(), Value: v.Action.GetArchived()})
+		}
+	case *events.Pin:
+		if !v.FromFullSync {
+			emit("chat.pin", v.Timestamp, chatStateEvent{Chat: v.JID.String(), Value: v.Action.GetPinned()})
+		}
+	case *events.Mute:
+		if !v.FromFullSync {
+			evt := chatStateEvent{Chat: v.JID.String(), Value: v.Action.GetMuted()}
+			if end := v.Action.GetMuteEndTimestamp(); end > 0 {
+				until := time.UnixMilli(end)
+				evt.Until = &until
+			}
+			emit("chat.mute", v.Timestamp, evt)
+		}
+	case *events.MarkChatAsRead:
+		if !v.FromFullSync {
+			if v.Action.GetRead() {
+				forgetChat(v.JID)
+			}
+			emit("chat.read", v.Timestamp, chatStateEvent{Chat: v.JID.String(), Value: v.Action.GetRead()})
+		}
+	case *events.ClearChat:
+		if !v.FromFullSync {
+			forgetChat(v.JID)
+			emit("chat.clear", v.Timestamp, chatStateEvent{Chat: v.JID.String(), Value: true})
+		}
+	case *events.DeleteChat:
+		if !v.FromFullSync {
+			forgetChat(v.JID)
+			emit("chat.delete", v.Timestamp, chatStateEvent{Chat: v.JID.String(), Value: true})
+		}
+	}
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+	"wa-common/phone"
+)
+
+/* ---------- Bot commands ---------- */
+
+// Pesan yang diawali prefix (default "!", mis. "!cek 0812...") diparse jadi
+// nama perintah dan argumen, lalu dijalankan oleh handler Go yang didaftarkan
+// lewat registerCommand. Perintah yang tidak dikenal diteruskan ke command
+// webhook kalau diset. Nilai awal dari env:
+//
+//	COMMAND_PREFIX=!/              satu atau beberapa karakter prefix
+//	COMMAND_OWNERS=628111,628222   nomor owner (nomor gateway sendiri selalu owner)
+//	COMMAND_WEBHOOK=https://...    tujuan perintah yang tidak ada handler Go-nya
+//
+// lalu bisa diubah lewat PUT /commands/config dan disimpan di gateway.db.
+// Setelah tersimpan, env tidak dibaca lagi. Nomor owner yang tidak valid di
+// env dilewati (dicatat di log).
+type commandConfig struct {
+	Enabled  bool                       `json:"enabled"`
+	Prefix   string                     `json:"prefix"`
+	Owners   []string                   `json:"owners"`
+	Webhook  string                     `json:"webhook"`
+	Commands map[string]commandOverride `json:"commands,omitempty"`
+}
+
+// commandOverride mengganti izin/cooldown bawaan, atau mendeklarasikan
+// perintah webhook supaya muncul di !help.
+type commandOverride struct {
+	Permission  string `json:"permission,omitempty"` // everyone | admin | owner
+	Cooldown    string `json:"cooldown,omitempty"`   // mis. "30s"
+	Description string `json:"description,omitempty"`
+	Usage       string `json:"usage,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+
+	cooldown time.Duration
+}
+
+const (
+	permEveryone = "everyone"
+	permAdmin    = "admin" // admin grup; di chat pribadi sama dengan owner
+	permOwner    = "owner"
+)
+
+var permLevel = map[string]int{permEveryone: 0, permAdmin: 1, permOwner: 2}
+
+// command adalah perintah bawaan gateway. Handler mengembalikan teks balasan;
+// string kosong berarti tidak membalas.
+type command struct {
+	Name        string
+	Aliases     []string
+	Usage       string
+	Description string
+	Permission  string
+	Cooldown    time.Duration
+	PrivateOnly bool // abaikan kalau dikirim di grup
+	Handler     func(c *commandCall) (string, error)
+}
+
+// commandCall adalah satu pemanggilan perintah.
+type commandCall struct {
+	Name    string           `json:"command"`
+	Args    []string         `json:"args"`
+	Raw     string           `json:"raw"` // teks setelah nama perintah, apa adanya
+	Level   string           `json:"permission"`
+	Event   msgEvent         `json:"message"`
+	Message *waProto.Message `json:"-"`
+}
+
+const commandConfigKey = "commands"
+
+var (
+	commandMu    sync.RWMutex
+	commandCfg   commandConfig
+	commandOwner map[string]bool
+	commandsByID = map[string]*command{} // nama dan alias
+	commandList  []*command
+
+	cooldownMu  sync.Mutex
+	cooldowns   = map[string]time.Time{} // perintah|nomor -> boleh lagi setelah
+	groupAdmins = newTTLCache(time.Minute)
+)
+
+func registerCommandRoutes() {
+	http.HandleFunc("GET /commands", listCommandsHandler)
+	http.HandleFunc("GET /commands/config", getCommandConfigHandler)
+	http.HandleFunc("PUT /commands/config", putCommandConfigHandler)
+}
+
+// registerCommand dipanggil dari init() di file yang menyediakan perintah.
+func registerCommand(c *command) {
+	if c.Permission == "" {
+		c.Permission = permEveryone
+	}
+	commandList = append(commandList, c)
+	for _, name := range append([]string{c.Name}, c.Aliases...) {
+		commandsByID[strings.ToLower(name)] = c
+	}
+}
+
+func init() {
+	registerCommand(&command{
+		Name:        "help",
+		Aliases:     []string{"menu"},
+		Usage:       "help [perintah]",
+		Description: "daftar perintah",
+		Handler:     helpCommand,
+	})
+}
+
+// loadCommandConfig dipanggil sekali setelah gateway.db dibuka.
+func loadCommandConfig() error {
+	cfg := commandConfig{
+		Enabled: envBool("COMMANDS", true),
+		Prefix:  os.Getenv("COMMAND_PREFIX"),
+		Webhook: os.Getenv("COMMAND_WEBHOOK"),
+	}
+	cfg.Owners = envPhones("COMMAND_OWNERS")
+	if _, err := loadSetting(commandConfigKey, &cfg); err != nil {
+		return err
+	}
+	return setCommandConfig(cfg)
+}
+
+func setCommandConfig(cfg commandConfig) error {
+	if cfg.Prefix = strings.TrimSpace(cfg.Prefix); cfg.Prefix == "" {
+		cfg.Prefix = "!"
+	}
+	owners := make(map[string]bool, len(cfg.Owners))
+	normalized := make([]string, 0, len(cfg.Owners))
+	for _, raw := range cfg.Owners {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		jid, err := phone.NormalizeJID(raw)
+		if err != nil {
+			return err
+		}
+		owners[jid.User] = true
+		normalized = append(normalized, jid.User)
+	}
+	cfg.Owners = normalized
+	overrides := make(map[string]commandOverride, len(cfg.Commands))
+	for name, o := range cfg.Commands {
+		if _, ok := permLevel[o.Permission]; o.Permission != "" && !ok {
+			return fmt.Errorf("command %q: permission must be everyone, admin or owner", name)
+		}
+		if o.Cooldown != "" {
+			d, err := time.ParseDuration(o.Cooldown)
+			if err != nil || d < 0 {
+				return fmt.Errorf("command %q: invalid cooldown %q", name, o.Cooldown)
+			}
+			o.cooldown = d
+		}
+		overrides[strings.ToLower(name)] = o
+	}
+	cfg.Commands = overrides
+	commandMu.Lock()
+	commandCfg, commandOwner = cfg, owners
+	commandMu.Unlock()
+	return nil
+}
+
+/* ---------- Dispatch ---------- */
+
+// parseCommand memecah "!cek 0812 345" jadi ("cek", ["0812","345"], "0812 345").
+func parseCommand(text, prefix string) (name string, args []string, raw string, ok bool) {
+	text = strings.TrimSpace(text)
+	if text == "" || !strings.ContainsRune(prefix, []rune(text)[0]) {
+		return "", nil, "", false
+	}
+	body := strings.TrimSpace(string([]rune(text)[1:]))
+	name, raw, _ = strings.Cut(body, " ")
+	if name, _, _ = strings.Cut(name, "\n"); name == "" {
+		return "", nil, "", false
+	}
+	raw = strings.TrimSpace(strings.TrimPrefix(body, name))
+	return strings.ToLower(name), strings.Fields(raw), raw, true
+}
+
+// handleCommand mengembalikan true kalau pesan adalah perintah yang dikenal
+// (bawaan, dideklarasikan di config, atau ada command webhook). Eksekusi
+// berjalan di goroutine.
+func handleCommand(e msgEvent, m *waProto.Message) bool {
+	commandMu.RLock()
+	cfg := commandCfg
+	commandMu.RUnlock()
+	if !cfg.Enabled || e.FromMe || e.Type != "text" {
+		return false
+	}
+	name, args, raw, ok := parseCommand(e.Text, cfg.Prefix)
+	if !ok {
+		return false
+	}
+	cmd := commandsByID[name]
+	if cmd != nil {
+		name = cmd.Name
+	}
+	override, declared := cfg.Commands[name]
+	if cmd == nil && !declared && cfg.Webhook == "" {
+		return false
+	}
+	if override.Disabled {
+		return false
+	}
+	call := &commandCall{Name: name, Args: args, Raw: raw, Event: e, Message: m}
+	go runCommand(cfg, cmd, override, call)
+	return true
+}
+
+// commandPolicy mengembalikan izin dan cooldown yang berlaku: bawaan
+// perintah (perintah webhook: everyone tanpa cooldown) lalu override config.
+func commandPolicy(cmd *command, o commandOverride) (perm string, cooldown time.Duration) {
+	perm = permEveryone
+	if cmd != nil {
+		perm, cooldown = cmd.Permission, cmd.Cooldown
+	}
+	if o.Permission != "" {
+		perm = o.Permission
+	}
+	if o.Cooldown != "" {
+		cooldown = o.cooldown
+	}
+	return perm, cooldown
+}
+
+func runCommand(cfg commandConfig, cmd *command, o commandOverride, call *commandCall) {
+	perm, cooldown := commandPolicy(cmd, o)
+
+	call.Level = senderLevel(call.Event)
+	result := map[string]interface{}{"command": call.Name, "args": call.Args, "chat": call.Event.Chat, "sender": call.Event.Sender}
+	defer func() { emit("command", time.Now(), result) }()
+
+	if cmd != nil && cmd.PrivateOnly && call.Event.IsGroup {
+		result["denied"] = "private_only"
+		return
+	}
+
+	if permLevel[call.Level] < permLevel[perm] {
+		result["denied"] = perm
+		replyCommand(call, "⛔ Perintah ini khusus "+perm+".")
+		return
+	}
+	if wait := takeCooldown(call.Name, call.Event.Sender, cooldown); wait > 0 {
+		result["cooldown"] = wait.Round(time.Second).String()
+		return
+	}
+
+	var reply string
+	var err error
+	if cmd != nil {
+		reply, err = cmd.Handler(call)
+	} else {
+		reply, err = commandWebhook(cfg.Webhook, call)
+	}
+	if err != nil {
+		result["error"] = err.Error()
+		reply = "❌ " + err.Error()
+	}
+	replyCommand(call, reply)
+}
+
+// senderLevel: owner kalau nomornya di COMMAND_OWNERS atau nomor gateway
+// sendiri, admin kalau admin grup tempat perintah dikirim.
+func senderLevel(e msgEvent) string {
+	sender, err := types.ParseJID(e.Sender)
+	if err != nil {
+		return permEveryone
+	}
+	// owner dicocokkan dengan nomor; e.Phone sudah diterjemahkan dari @lid
+	commandMu.RLock()
+	owner := e.Phone != "" && commandOwner[e.Phone]
+	commandMu.RUnlock()
+	self := (cli.Store.ID != nil && cli.Store.ID.User == e.Phone) ||
+		(!cli.Store.LID.IsEmpty() && cli.Store.LID.User == sender.User)
+	if owner || self {
+		return permOwner
+	}
+	if e.IsGroup && isGroupAdmin(e.Chat, sender.User) {
+		return permAdmin
+	}
+	return permEveryone
+}
+
+func isGroupAdmin(chat, user string) bool {
+	cached, ok := groupAdmins.get(chat)
+	if !ok {
+		jid, err := types.ParseJID(chat)
+		if err != nil {
+			return false
+		}
+		info, err := cli.GetGroupInfo(jid)
+		if err != nil {
+			return false
+		}
+		admins := map[string]bool{}
+		for _, p := range info.Participants {
+			if p.IsAdmin || p.IsSuperAdmin {
+				admins[p.JID.User] = true
+				admins[p.PhoneNumber.User] = true
+				admins[p.LID.User] = true
+			}
+		}
+		cached = admins
+		groupAdmins.set(chat, admins)
+	}
+	return cached.(map[string]bool)[user]
+}
+
+// takeCooldown mengembalikan sisa waktu tunggu; 0 berarti boleh jalan dan
+// cooldown baru langsung dicatat.
+func takeCooldown(name, sender string, d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	key := name + "|" + sender
+	now := time.Now()
+	cooldownMu.Lock()
+	defer cooldownMu.Unlock()
+	if until, ok := cooldowns[key]; ok && now.Before(until) {
+		return until.Sub(now)
+	}
+	for k, until := range cooldowns {
+		if now.After(until) {
+			delete(cooldowns, k)
+		}
+	}
+	cooldowns[key] = now.Add(d)
+	return 0
+}
+
+func replyCommand(call *commandCall, text string) {
+	if text == "" {
+		return
+	}
+	chat, err := types.ParseJID(call.Event.Chat)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, _ = sendMessage(ctx, chat, &waProto.Message{ExtendedTextMessage: &waProto.ExtendedTextMessage{
+		Text:        proto.String(text),
+		ContextInfo: quoteContext(call.Event, call.Message),
+	}})
+}
+
+// commandWebhook mengirim {"command","args","raw","permission","message"}
+// dan membaca balasan {"reply":"..."} (boleh kosong).
+func commandWebhook(url string, call *commandCall) (string, error) {
+	body, _ := json.Marshal(call)
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("command webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("command webhook returned %s", resp.Status)
+	}
+	var out struct {
+		Reply string `json:"reply"`
+	}
+	if resp.ContentLength != 0 {
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+	}
+	return out.Reply, nil
+}
+
+/* ---------- help ---------- */
+
+type commandInfo struct {
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Usage       string   `json:"usage,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Permission  string   `json:"permission"`
+	Cooldown    string   `json:"cooldown,omitempty"`
+	Source      string   `json:"source"` // builtin | webhook
+}
+
+// availableCommands menggabungkan perintah bawaan dengan yang dideklarasikan
+// di config, setelah override izin/cooldown diterapkan.
+func availableCommands(cfg commandConfig) []commandInfo {
+	var out []commandInfo
+	seen := map[string]bool{}
+	for _, c := range commandList {
+		info := commandInfo{Name: c.Name, Aliases: c.Aliases, Usage: c.Usage, Description: c.Description,
+			Permission: c.Permission, Source: "builtin"}
+		if c.Cooldown > 0 {
+			info.Cooldown = c.Cooldown.String()
+		}
+		seen[c.Name] = true
+		if o, ok := cfg.Commands[c.Name]; ok {
+			if o.Disabled {
+				continue
+			}
+			applyOverride(&info, o)
+		}
+		out = append(out, info)
+	}
+	for name, o := range cfg.Commands {
+		if seen[name] || o.Disabled {
+			continue
+		}
+		info := commandInfo{Name: name, Permission: permEveryone, Source: "webhook"}
+		applyOverride(&info, o)
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func applyOverride(info *commandInfo, o commandOverride) {
+	if o.Permission != "" {
+		info.Permission = o.Permission
+	}
+	if o.Cooldown != "" {
+		info.Cooldown = o.Cooldown
+	}
+	if o.Description != "" {
+		info.Description = o.Description
+	}
+	if o.Usage != "" {
+		info.Usage = o.Usage
+	}
+}
+
+func helpCommand(c *commandCall) (string, error) {
+	commandMu.RLock()
+	cfg := commandCfg
+	commandMu.RUnlock()
+	prefix := "!"
+	if cfg.Prefix != "" {
+		prefix = string([]rune(cfg.Prefix)[0])
+	}
+
+	var b strings.Builder
+	for _, info := range availableCommands(cfg) {
+		if permLevel[c.Level] < permLevel[info.Permission] {
+			continue
+		}
+		if cmd := commandsByID[info.Name]; cmd != nil && cmd.PrivateOnly && c.Event.IsGroup {
+			continue
+		}
+		if len(c.Args) > 0 {
+			if !strings.EqualFold(info.Name, c.Args[0]) && !containsString(info.Aliases, strings.ToLower(c.Args[0])) {
+				continue
+			}
+			usage := info.Usage
+			if usage == "" {
+				usage = info.Name
+			}
+			fmt.Fprintf(&b, "*%s%s*\n%s", prefix, usage, info.Description)
+			if len(info.Aliases) > 0 {
+				fmt.Fprintf(&b, "\nAlias: %s", strings.Join(info.Aliases, ", "))
+			}
+			return b.String(), nil
+		}
+		fmt.Fprintf(&b, "• *%s%s*", prefix, info.Name)
+		if info.Description != "" {
+			b.WriteString(" — " + info.Description)
+		}
+		b.WriteString("\n")
+	}
+	if len(c.Args) > 0 {
+		return "Perintah " + c.Args[0] + " tidak ada. Ketik " + prefix + "help untuk daftar perintah.", nil
+	}
+	return "📋 Daftar perintah:\n" + strings.TrimRight(b.String(), "\n"), nil
+}
+
+/* ---------- Commands API ---------- */
+
+func listCommandsHandler(w http.ResponseWriter, r *http.Request) {
+	commandMu.RLock()
+	cfg := commandCfg
+	commandMu.RUnlock()
+	writeJSON(w, map[string]interface{}{"prefix": cfg.Prefix, "commands": availableCommands(cfg)})
+}
+
+func getCommandConfigHandler(w http.ResponseWriter, r *http.Request) {
+	commandMu.RLock()
+	cfg := commandCfg
+	commandMu.RUnlock()
+	writeJSON(w, cfg)
+}
+
+// putCommandConfigHandler menerima
+// {"enabled":true,"prefix":"!/","owners":["0812..."],"webhook":"https://...",
+// "commands":{"ongkir":{"description":"cek ongkir","cooldown":"30s"}}}.
+func putCommandConfigHandler(w http.ResponseWriter, r *http.Request) {
+	var cfg commandConfig
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		writeError(w, 400, "bad json")
+		return
+	}
+	if err := setCommandConfig(cfg); err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	commandMu.RLock()
+	cfg = commandCfg
+	commandMu.RUnlock()
+	if err := saveSetting(commandConfigKey, cfg); err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, cfg)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		text, prefix string
+		name         string
+		args         []string
+		raw          string
+		ok           bool
+	}{
+		{text: "!cek 0812 345", prefix: "!", name: "cek", args: []string{"0812", "345"}, raw: "0812 345", ok: true},
+		{text: "  !PING  ", prefix: "!", name: "ping", args: []string{}, ok: true},
+		{text: "/help cek", prefix: "!/", name: "help", args: []string{"cek"}, raw: "cek", ok: true},
+		{text: "! cek", prefix: "!", name: "cek", args: []string{}, ok: true},
+		{text: "!\ncek", prefix: "!", name: "cek", args: []string{}, ok: true},
+		{text: "!kirim  halo   dunia", prefix: "!", name: "kirim", args: []string{"halo", "dunia"}, raw: "halo   dunia", ok: true},
+		{text: "!Cek\nbaris dua", prefix: "!", name: "cek", args: []string{"baris", "dua"}, raw: "baris dua", ok: true},
+		{text: "🤖status", prefix: "🤖", name: "status", args: []string{}, ok: true},
+		{text: "halo", prefix: "!"},
+		{text: "/help", prefix: "!"},
+		{text: "!", prefix: "!"},
+		{text: "", prefix: "!"},
+	}
+	for _, tt := range tests {
+		name, args, raw, ok := parseCommand(tt.text, tt.prefix)
+		if ok != tt.ok {
+			t.Errorf("parseCommand(%q, %q) ok = %v, want %v", tt.text, tt.prefix, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if args == nil {
+			args = []string{}
+		}
+		if name != tt.name || raw != tt.raw || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("parseCommand(%q, %q) = %q, %q, %q; want %q, %q, %q",
+				tt.text, tt.prefix, name, args, raw, tt.name, tt.args, tt.raw)
+		}
+	}
+}
+
+func TestCommandPolicy(t *testing.T) {
+	builtin := &command{Name: "cek", Permission: permAdmin, Cooldown: time.Minute}
+	tests := []struct {
+		name     string
+		cmd      *command
+		o        commandOverride
+		perm     string
+		cooldown time.Duration
+	}{
+		{name: "bawaan", cmd: builtin, perm: permAdmin, cooldown: time.Minute},
+		{name: "override izin", cmd: builtin, o: commandOverride{Permission: permOwner}, perm: permOwner, cooldown: time.Minute},
+		{name: "override cooldown", cmd: builtin, o: commandOverride{Cooldown: "30s", cooldown: 30 * time.Second}, perm: permAdmin, cooldown: 30 * time.Second},
+		{name: "cooldown dimatikan", cmd: builtin, o: commandOverride{Cooldown: "0s"}, perm: permAdmin},
+		{name: "webhook", perm: permEveryone},
+		{name: "webhook dengan override", o: commandOverride{Permission: permOwner, Cooldown: "1m", cooldown: time.Minute}, perm: permOwner, cooldown: time.Minute},
+	}
+	for _, tt := range tests {
+		perm, cooldown := commandPolicy(tt.cmd, tt.o)
+		if perm != tt.perm || cooldown != tt.cooldown {
+			t.Errorf("%s: commandPolicy = %s, %v; want %s, %v", tt.name, perm, cooldown, tt.perm, tt.cooldown)
+		}
+	}
+
+	// level pengirim harus minimal setara izin perintah
+	for _, tt := range []struct {
+		level, perm string
+		allowed     bool
+	}{
+		{permEveryone, permEveryone, true},
+		{permEveryone, permAdmin, false},
+		{permAdmin, permAdmin, true},
+		{permAdmin, permOwner, false},
+		{permOwner, permAdmin, true},
+		{permOwner, permOwner, true},
+	} {
+		if got := permLevel[tt.level] >= permLevel[tt.perm]; got != tt.allowed {
+			t.Errorf("level %s for %s: allowed = %v, want %v", tt.level, tt.perm, got, tt.allowed)
+		}
+	}
+}
+
+func TestTakeCooldown(t *testing.T) {
+	cooldownMu.Lock()
+	cooldowns = map[string]time.Time{}
+	cooldownMu.Unlock()
+	const alice, bob = "628111@s.whatsapp.net", "628222@s.whatsapp.net"
+
+	if wait := takeCooldown("cek", alice, 0); wait != 0 {
+		t.Fatalf("no cooldown: wait = %v", wait)
+	}
+	if wait := takeCooldown("cek", alice, 0); wait != 0 {
+		t.Fatalf("no cooldown, second call: wait = %v", wait)
+	}
+	if wait := takeCooldown("cek", alice, time.Minute); wait != 0 {
+		t.Fatalf("first call: wait = %v, want 0", wait)
+	}
+	if wait := takeCooldown("cek", alice, time.Minute); wait <= 0 || wait > time.Minute {
+		t.Fatalf("second call: wait = %v, want (0, 1m]", wait)
+	}
+	if wait := takeCooldown("cek", bob, time.Minute); wait != 0 {
+		t.Errorf("other sender: wait = %v, want 0", wait)
+	}
+	if wait := takeCooldown("help", alice, time.Minute); wait != 0 {
+		t.Errorf("other command: wait = %v, want 0", wait)
+	}
+
+	// cooldown yang sudah lewat boleh jalan lagi dan entri kedaluwarsa lain dibersihkan
+	cooldownMu.Lock()
+	cooldowns["cek|"+alice] = time.Now().Add(-time.Second)
+	cooldowns["lama|"+bob] = time.Now().Add(-time.Hour)
+	cooldownMu.Unlock()
+	if wait := takeCooldown("cek", alice, time.Minute); wait != 0 {
+		t.Errorf("expired cooldown: wait = %v, want 0", wait)
+	}
+	cooldownMu.Lock()
+	_, stale := cooldowns["lama|"+bob]
+	until := cooldowns["cek|"+alice]
+	cooldownMu.Unlock()
+	if stale {
+		t.Error("expired cooldown entry was not cleaned up")
+	}
+	if time.Until(until) <= 0 {
+		t.Errorf("cooldown was not renewed: until = %v", until)
+	}
+}
+
+func TestSetCommandConfig(t *testing.T) {
+	t.Setenv("DEFAULT_COUNTRY_CODE", "")
+	commandMu.RLock()
+	saved, savedOwners := commandCfg, commandOwner
+	commandMu.RUnlock()
+	defer func() {
+		commandMu.Lock()
+		commandCfg, commandOwner = saved, savedOwners
+		commandMu.Unlock()
+	}()
+
+	err := setCommandConfig(commandConfig{
+		Prefix:   "  ",
+		Owners:   []string{"0812-3456-789", " ", "+62 811 111 111"},
+		Commands: map[string]commandOverride{"Ongkir": {Permission: permAdmin, Cooldown: "30s"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commandMu.RLock()
+	cfg, owners := commandCfg, commandOwner
+	commandMu.RUnlock()
+	if cfg.Prefix != "!" {
+		t.Errorf("prefix = %q, want default !", cfg.Prefix)
+	}
+	if want := []string{"628123456789", "62811111111"}; !reflect.DeepEqual(cfg.Owners, want) {
+		t.Errorf("owners = %v, want %v", cfg.Owners, want)
+	}
+	if !owners["628123456789"] || !owners["62811111111"] {
+		t.Errorf("owner set = %v", owners)
+	}
+	if o := cfg.Commands["ongkir"]; o.Permission != permAdmin || o.cooldown != 30*time.Second {
+		t.Errorf("override = %+v, want admin with 30s cooldown", o)
+	}
+
+	for name, bad := range map[string]commandConfig{
+		"owner":      {Owners: []string{"bukan nomor"}},
+		"permission": {Commands: map[string]commandOverride{"x": {Permission: "root"}}},
+		"cooldown":   {Commands: map[string]commandOverride{"x": {Cooldown: "sebentar"}}},
+		"negatif":    {Commands: map[string]commandOverride{"x": {Cooldown: "-1s"}}},
+	} {
+		if err := setCommandConfig(bad); err == nil {
+			t.Errorf("%s: setCommandConfig succeeded, want error", name)
+		}
+	}
+}
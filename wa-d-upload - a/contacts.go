@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"wa-common/phone"
+)
+
+/* ---------- Contacts ---------- */
+
+// defaultContactTTL bisa diganti lewat env CONTACT_CACHE_TTL (mis. "30m").
+const defaultContactTTL = time.Hour
+
+// maxCheckBatch membatasi jumlah nomor per request /contacts/check.
+const maxCheckBatch = 500
+
+type numberCheck struct {
+	Query        string `json:"query"`
+	JID          string `json:"jid,omitempty"`
+	IsOnWhatsApp bool   `json:"is_on_whatsapp"`
+	BusinessName string `json:"business_name,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type contactProfile struct {
+	JID          string    `json:"jid"`
+	PushName     string    `json:"push_name,omitempty"`
+	FullName     string    `json:"full_name,omitempty"`
+	BusinessName string    `json:"business_name,omitempty"`
+	About        string    `json:"about,omitempty"`
+	PictureURL   string    `json:"picture_url,omitempty"`
+	PictureID    string    `json:"picture_id,omitempty"`
+	IsBusiness   bool      `json:"is_verified_business"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// ttlCache adalah cache in-memory sederhana supaya lookup berulang tidak
+// membanjiri server WhatsApp.
+type ttlCache struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	items map[string]cacheEntry
+}
+
+func newTTLCache(ttl time.Duration) *ttlCache {
+	return &ttlCache{ttl: ttl, items: make(map[string]cacheEntry)}
+}
+
+func (c *ttlCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok || time.Now().After(e.expires) {
+		delete(c.items, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (c *ttlCache) set(key string, value interface{}) {
+	c.mu.Lock()
+	c.items[key] = cacheEntry{value: value, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+}
+
+var (
+	numberCache  = newTTLCache(contactTTL())
+	profileCache = newTTLCache(contactTTL())
+)
+
+func contactTTL() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("CONTACT_CACHE_TTL")); err == nil && d > 0 {
+		return d
+	}
+	return defaultContactTTL
+}
+
+func registerContactRoutes() {
+	http.HandleFunc("POST /contacts/check", checkContactsHandler)
+	http.HandleFunc("GET /contacts/{jid}", contactProfileHandler)
+}
+
+// checkContactsHandler menerima {"phones":["0812...", "+62 812-..."]} dan hanya
+// menanyakan ke server nomor yang belum ada di cache.
+func checkContactsHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireLogin(w) {
+		return
+	}
+	var body struct {
+		Phones []string `json:"phones"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Phones) == 0 {
+		writeError(w, 400, "bad json")
+		return
+	}
+	if len(body.Phones) > maxCheckBatch {
+		writeError(w, 400, "too many phones in one request")
+		return
+	}
+
+	results := make([]numberCheck, len(body.Phones))
+	var pending []string
+	pendingIdx := map[string][]int{}
+	for i, raw := range body.Phones {
+		results[i].Query = raw
+		digits, err := phone.Normalize(raw)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		number := "+" + digits
+		if cached, ok := numberCache.get(number); ok {
+			res := cached.(numberCheck)
+			res.Query = raw
+			results[i] = res
+			continue
+		}
+		if _, seen := pendingIdx[number]; !seen {
+			pending = append(pending, number)
+		}
+		pendingIdx[number] = append(pendingIdx[number], i)
+	}
+
+	if len(pending) > 0 {
+		resp, err := cli.IsOnWhatsApp(pending)
+		if err != nil {
+			writeError(w, 500, err.Error())
+			return
+		}
+		for _, item := range resp {
+			res := numberCheck{IsOnWhatsApp: item.IsIn}
+			if item.IsIn {
+				res.JID = item.JID.String()
+			}
+			if item.VerifiedName != nil {
+				res.BusinessName = item.VerifiedName.Details.GetVerifiedName()
+			}
+			numberCache.set(item.Query, res)
+			for _, i := range pendingIdx[item.Query] {
+				res.Query = results[i].Query
+				results[i] = res
+			}
+		}
+	}
+	writeJSON(w, map[string]interface{}{"results": results})
+}
+
+func contactProfileHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireLogin(w) {
+		return
+	}
+	jids, err := parseUserJIDs([]string{r.PathValue("jid")})
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	jid := jids[0].ToNonAD()
+	if cached, ok := profileCache.get(jid.String()); ok && r.URL.Query().Get("refresh") == "" {
+		writeJSON(w, cached)
+		return
+	}
+	profile, err := fetchProfile(r.Context(), jid)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	profileCache.set(jid.String(), profile)
+	writeJSON(w, profile)
+}
+
+func fetchProfile(ctx context.Context, jid types.JID) (contactProfile, error) {
+	p := contactProfile{JID: jid.String(), FetchedAt: time.Now()}
+	if c, err := cli.Store.Contacts.GetContact(ctx, jid); err == nil && c.Found {
+		p.PushName = c.PushName
+		p.FullName = c.FullName
+		p.BusinessName = c.BusinessName
+	}
+
+	infos, err := cli.GetUserInfo([]types.JID{jid})
+	if err != nil {
+		return p, err
+	}
+	if info, ok := infos[jid]; ok {
+		p.About = info.Status
+		p.PictureID = info.PictureID
+		if info.VerifiedName != nil {
+			p.IsBusiness = true
+			if name := info.VerifiedName.Details.GetVerifiedName(); name != "" {
+				p.BusinessName = name
+			}
+		}
+	}
+
+	pic, err := cli.GetProfilePictureInfo(jid, &whatsmeow.GetProfilePictureParams{})
+	switch {
+	case err == nil && pic != nil:
+		p.PictureURL = pic.URL
+		p.PictureID = pic.ID
+	case errors.Is(err, whatsmeow.ErrProfilePictureUnauthorized), errors.Is(err, whatsmeow.ErrProfilePictureNotSet):
+		// foto disembunyikan lewat privasi atau memang tidak ada
+	case err != nil:
+		return p, err
+	}
+	return p, nil
+}
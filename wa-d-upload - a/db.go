@@ -0,0 +1,109 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+/* ---------- Gateway DB ---------- */
+
+// gwDB menyimpan data milik gateway sendiri (poll, status, dsb.), terpisah dari
+// tabel session whatsmeow di session.db. Path bisa diganti lewat env GATEWAY_DB.
+var gwDB *sql.DB
+
+var gatewaySchema = []string{
+	`CREATE TABLE IF NOT EXISTS polls (
+		id               TEXT PRIMARY KEY,
+		chat             TEXT NOT NULL,
+		sender           TEXT NOT NULL,
+		question         TEXT NOT NULL,
+		selectable_count INTEGER NOT NULL,
+		created_at       INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS poll_options (
+		poll_id TEXT NOT NULL REFERENCES polls(id) ON DELETE CASCADE,
+		idx     INTEGER NOT NULL,
+		name    TEXT NOT NULL,
+		hash    BLOB NOT NULL,
+		PRIMARY KEY (poll_id, idx)
+	)`,
+	`CREATE TABLE IF NOT EXISTS poll_votes (
+		poll_id    TEXT NOT NULL REFERENCES polls(id) ON DELETE CASCADE,
+		voter      TEXT NOT NULL,
+		options    TEXT NOT NULL,
+		updated_at INTEGER NOT NULL,
+		PRIMARY KEY (poll_id, voter)
+	)`,
+	`CREATE TABLE IF NOT EXISTS statuses (
+		id        TEXT PRIMARY KEY,
+		sender    TEXT NOT NULL,
+		push_name TEXT NOT NULL,
+		type      TEXT NOT NULL,
+		text      TEXT NOT NULL,
+		has_media INTEGER NOT NULL,
+		message   BLOB NOT NULL,
+		posted_at INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS statuses_sender ON statuses (sender, posted_at)`,
+	`CREATE TABLE IF NOT EXISTS settings (
+		key   TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS flow_sessions (
+		chat       TEXT NOT NULL,
+		user       TEXT NOT NULL,
+		flow       TEXT NOT NULL,
+		node       TEXT NOT NULL,
+		vars       TEXT NOT NULL DEFAULT '{}',
+		status     TEXT NOT NULL, -- active | handoff
+		started_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL,
+		PRIMARY KEY (chat, user)
+	)`,
+	`CREATE INDEX IF NOT EXISTS flow_sessions_expires ON flow_sessions (expires_at)`,
+}
+
+func openGatewayDB() error {
+	path := os.Getenv("GATEWAY_DB")
+	if path == "" {
+		path = "gateway.db"
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return err
+	}
+	for _, stmt := range gatewaySchema {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return err
+		}
+	}
+	gwDB = db
+	return nil
+}
+
+// loadSetting membaca pengaturan runtime (JSON) ke v. Kalau belum pernah
+// disimpan, v dibiarkan apa adanya dan found bernilai false.
+func loadSetting(key string, v interface{}) (found bool, err error) {
+	var raw string
+	err = gwDB.QueryRow(`SELECT value FROM settings WHERE key = ?`, key).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(raw), v)
+}
+
+func saveSetting(key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = gwDB.Exec(`INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`, key, string(raw))
+	return err
+}
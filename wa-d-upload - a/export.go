@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"wa-common/chatexport"
+	"wa-common/phone"
+)
+
+/* ---------- Export API ---------- */
+
+func registerExportRoutes() {
+	http.HandleFunc("GET /chats/{jid}/export", exportChatHandler)
+}
+
+// exportChatHandler: GET /chats/{jid}/export?format=txt&from=2025-01-01&to=2025-01-31&zip=1&media=1.
+// Media hanya ikut kalau zip=1; lampiran hasil import selalu disertakan,
+// media WhatsApp hanya kalau gateway sedang login.
+func exportChatHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireArchive(w) {
+		return
+	}
+	jid, err := phone.NormalizeJID(r.PathValue("jid"))
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	q := r.URL.Query()
+	opt := chatexport.Options{
+		Chat:     jid.String(),
+		Format:   strings.ToLower(q.Get("format")),
+		Zip:      q.Get("zip") == "1" || q.Get("zip") == "true",
+		Location: time.Local,
+	}
+	if opt.Format == "" {
+		opt.Format = "json"
+	}
+	if _, ok := chatexport.Formats[opt.Format]; !ok {
+		writeError(w, 400, "format must be json, csv, html or txt")
+		return
+	}
+	opt.Media = opt.Zip && (q.Get("media") == "1" || q.Get("media") == "true")
+	if opt.From, err = chatexport.ParseTime(q.Get("from"), false, opt.Location); err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	if opt.To, err = chatexport.ParseTime(q.Get("to"), true, opt.Location); err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	if cli.Store.PushName != "" {
+		opt.SelfName = cli.Store.PushName
+	}
+
+	chat, err := chatexport.Load(gwDB, opt)
+	if errors.Is(err, chatexport.ErrChatNotFound) {
+		writeError(w, 404, err.Error())
+		return
+	} else if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	var download func(*waProto.Message) ([]byte, error)
+	if opt.Media && cli.IsLoggedIn() {
+		download = func(m *waProto.Message) ([]byte, error) {
+			ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+			defer cancel()
+			return cli.DownloadAny(ctx, m)
+		}
+	}
+
+	name := "chat-" + jid.User
+	if opt.Zip {
+		w.Header().Set("Content-Type", "application/zip")
+		name += ".zip"
+	} else {
+		w.Header().Set("Content-Type", chatexport.Formats[opt.Format])
+		name += "." + opt.Format
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	// Ditulis langsung ke response supaya zip berisi media tidak ditampung
+	// di memori. Begitu mulai menulis status 200 sudah terkirim, jadi error
+	// di tengah jalan hanya bisa dicatat dan klien menerima file terpotong.
+	if err := chatexport.Write(w, chat, opt, download); err != nil {
+		log.Printf("export %s: %v", jid, err)
+	}
+}
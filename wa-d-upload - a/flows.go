@@ -0,0 +1,900 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+	"wa-common/phone"
+)
+
+/* ---------- Conversation flows ---------- */
+
+// Flow adalah state machine per pengguna. Sesi dimulai saat pesan cocok
+// dengan trigger (syaratnya sama dengan match di rules), lalu setiap balasan
+// pengguna menggerakkan sesi ke node berikutnya. Contoh (FLOWS_FILE=flows.yaml):
+//
+//	flows:
+//	  - name: cs
+//	    trigger: {keywords: [menu], chat_type: private}
+//	    start: menu
+//	    timeout: 30m
+//	    timeout_message: Sesi berakhir, ketik menu untuk mulai lagi.
+//	    cancel: [batal]
+//	    nodes:
+//	      menu:
+//	        type: choice
+//	        text: "Halo {{.Name}}, pilih:\n1. Tagihan\n2. Bicara dengan agen"
+//	        options:
+//	          - {key: "1", next: invoice}
+//	          - {key: "2", next: agent}
+//	      invoice:
+//	        type: input
+//	        text: Masukkan nomor invoice (6 digit)
+//	        var: invoice
+//	        validate: '^\d{6}$'
+//	        next: cek
+//	      cek:
+//	        type: webhook
+//	        url: https://crm.example/invoice   # boleh balas {"vars":{},"reply":"","next":""}
+//	        next: selesai
+//	      selesai:
+//	        type: end
+//	        text: "Status invoice {{.Vars.invoice}}: {{.Vars.status}}"
+//	      agent:
+//	        type: handoff
+//	        text: Mohon tunggu, agen kami akan membalas.
+//
+// Node: message, choice, input, branch, webhook, handoff, end. Selama
+// handoff bot diam sampai sesi dilepas lewat DELETE /flows/sessions/{jid}
+// atau handoff_timeout habis. Tanpa FLOWS_FILE, flow dikelola lewat PUT /flows.
+type flowDef struct {
+	Name           string               `json:"name" yaml:"name"`
+	Trigger        ruleMatch            `json:"trigger" yaml:"trigger"`
+	Start          string               `json:"start" yaml:"start"`
+	Timeout        string               `json:"timeout,omitempty" yaml:"timeout"` // default 30m
+	TimeoutMessage string               `json:"timeout_message,omitempty" yaml:"timeout_message"`
+	HandoffTimeout string               `json:"handoff_timeout,omitempty" yaml:"handoff_timeout"` // default 24h
+	Cancel         []string             `json:"cancel,omitempty" yaml:"cancel"`                   // kata untuk keluar dari flow
+	CancelMessage  string               `json:"cancel_message,omitempty" yaml:"cancel_message"`
+	Nodes          map[string]*flowNode `json:"nodes" yaml:"nodes"`
+
+	timeout, handoffTimeout time.Duration
+}
+
+type flowNode struct {
+	Type     string       `json:"type" yaml:"type"`
+	Text     string       `json:"text,omitempty" yaml:"text"`
+	Options  []flowOption `json:"options,omitempty" yaml:"options"`   // choice
+	Var      string       `json:"var,omitempty" yaml:"var"`           // input; choice menyimpan key pilihan
+	Validate string       `json:"validate,omitempty" yaml:"validate"` // regex untuk input
+	Invalid  string       `json:"invalid,omitempty" yaml:"invalid"`   // balasan kalau input/pilihan tidak valid
+	Branches []flowBranch `json:"branches,omitempty" yaml:"branches"`
+	Default  string       `json:"default,omitempty" yaml:"default"` // branch tanpa yang cocok
+	URL      string       `json:"url,omitempty" yaml:"url"`         // webhook
+	OnError  string       `json:"on_error,omitempty" yaml:"on_error"`
+	Next     string       `json:"next,omitempty" yaml:"next"`
+
+	tmpl, invalid *template.Template
+	re            *regexp.Regexp
+}
+
+type flowOption struct {
+	Key   string `json:"key" yaml:"key"`
+	Label string `json:"label,omitempty" yaml:"label"`
+	Next  string `json:"next" yaml:"next"`
+}
+
+// flowBranch cocok kalau variabel Var sama dengan Equals (tidak peka huruf
+// besar) atau cocok dengan Regex; tanpa keduanya cukup tidak kosong.
+type flowBranch struct {
+	Var    string `json:"var" yaml:"var"`
+	Equals string `json:"equals,omitempty" yaml:"equals"`
+	Regex  string `json:"regex,omitempty" yaml:"regex"`
+	Next   string `json:"next" yaml:"next"`
+
+	re *regexp.Regexp
+}
+
+type flowSet struct {
+	Flows []*flowDef `json:"flows" yaml:"flows"`
+}
+
+func (s flowSet) byName(name string) *flowDef {
+	for _, f := range s.Flows {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// triggered mengembalikan flow pertama yang trigger-nya cocok dengan pesan.
+func (s flowSet) triggered(e msgEvent) *flowDef {
+	for _, f := range s.Flows {
+		data := newRuleData(e)
+		if f.Trigger.matches(e, &data) {
+			return f
+		}
+	}
+	return nil
+}
+
+const (
+	flowsKey        = "flows"
+	flowActive      = "active"
+	flowHandoff     = "handoff"
+	maxFlowSteps    = 50 // pengaman kalau node message/branch membentuk loop
+	flowJanitorTick = time.Minute
+	flowQueueSize   = 16 // pesan yang boleh menunggu per sesi
+)
+
+var (
+	flowsMu     sync.RWMutex
+	flows       flowSet
+	flowJanitor sync.Once
+
+	// flowQueues berisi antrean per sesi (chat + user). Tiap antrean punya
+	// satu goroutine yang berhenti sendiri begitu antreannya kosong.
+	flowQueuesMu sync.Mutex
+	flowQueues   = map[string]chan flowJob{}
+)
+
+var flowNodeTypes = map[string]bool{
+	"message": true, "choice": true, "input": true, "branch": true, "webhook": true, "handoff": true, "end": true,
+}
+
+func registerFlowRoutes() {
+	http.HandleFunc("GET /flows", getFlowsHandler)
+	http.HandleFunc("PUT /flows", putFlowsHandler)
+	http.HandleFunc("POST /flows/reload", reloadFlowsHandler)
+	http.HandleFunc("GET /flows/sessions", listFlowSessionsHandler)
+	http.HandleFunc("DELETE /flows/sessions/{jid}", endFlowSessionHandler)
+}
+
+// loadFlows dipanggil sekali setelah gateway.db dibuka, sekaligus
+// menjalankan pembersih timeout.
+func loadFlows() error {
+	var set flowSet
+	if file := os.Getenv("FLOWS_FILE"); file != "" {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(raw, &set); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	} else if _, err := loadSetting(flowsKey, &set); err != nil {
+		return err
+	}
+	if err := compileFlows(&set); err != nil {
+		return err
+	}
+	flowsMu.Lock()
+	flows = set
+	flowsMu.Unlock()
+	flowJanitor.Do(func() {
+		go expireFlowSessions()
+	})
+	return nil
+}
+
+func compileFlows(set *flowSet) error {
+	seen := map[string]bool{}
+	for i, f := range set.Flows {
+		if f == nil || f.Name == "" {
+			return fmt.Errorf("flow #%d needs a name", i+1)
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("duplicate flow %q", f.Name)
+		}
+		seen[f.Name] = true
+		fail := func(format string, args ...interface{}) error {
+			return fmt.Errorf("flow %q: %s", f.Name, fmt.Sprintf(format, args...))
+		}
+		if err := f.Trigger.compile(); err != nil {
+			return fail("trigger: %v", err)
+		}
+		var err error
+		if f.timeout, err = durationOr(f.Timeout, 30*time.Minute); err != nil {
+			return fail("timeout: %v", err)
+		}
+		if f.handoffTimeout, err = durationOr(f.HandoffTimeout, 24*time.Hour); err != nil {
+			return fail("handoff_timeout: %v", err)
+		}
+		if f.Nodes[f.Start] == nil {
+			return fail("start node %q not found", f.Start)
+		}
+		exists := func(next string) bool { return next == "" || f.Nodes[next] != nil }
+		for name, n := range f.Nodes {
+			nfail := func(format string, args ...interface{}) error {
+				return fail("node %q: %s", name, fmt.Sprintf(format, args...))
+			}
+			if n == nil || !flowNodeTypes[n.Type] {
+				return nfail("type must be message, choice, input, branch, webhook, handoff or end")
+			}
+			if !exists(n.Next) || !exists(n.Default) || !exists(n.OnError) {
+				return nfail("next/default/on_error points to an unknown node")
+			}
+			switch n.Type {
+			case "choice":
+				if len(n.Options) == 0 {
+					return nfail("choice needs options")
+				}
+				for _, o := range n.Options {
+					if o.Key == "" || !exists(o.Next) {
+						return nfail("option %q needs a key and a valid next", o.Key)
+					}
+				}
+			case "input":
+				if n.Var == "" {
+					return nfail("input needs var")
+				}
+			case "webhook":
+				if n.URL == "" {
+					return nfail("webhook needs url")
+				}
+			case "branch":
+				for j := range n.Branches {
+					b := &n.Branches[j]
+					if b.Var == "" || !exists(b.Next) {
+						return nfail("branch #%d needs var and a valid next", j+1)
+					}
+					if b.Regex != "" {
+						if b.re, err = regexp.Compile(b.Regex); err != nil {
+							return nfail("bad regex: %v", err)
+						}
+					}
+				}
+			}
+			if n.Validate != "" {
+				if n.re, err = regexp.Compile(n.Validate); err != nil {
+					return nfail("bad validate regex: %v", err)
+				}
+			}
+			if n.tmpl, err = parseFlowTemplate(name, n.Text); err != nil {
+				return nfail("template: %v", err)
+			}
+			if n.invalid, err = parseFlowTemplate(name, n.Invalid); err != nil {
+				return nfail("template: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+func parseFlowTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return template.New(name).Option("missingkey=zero").Parse(text)
+}
+
+func durationOr(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+/* ---------- Sessions ---------- */
+
+type flowSession struct {
+	Chat      string            `json:"chat"`
+	User      string            `json:"user"`
+	Flow      string            `json:"flow"`
+	Node      string            `json:"node"`
+	Vars      map[string]string `json:"vars"`
+	Status    string            `json:"status"`
+	StartedAt time.Time         `json:"started_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+const flowSessionColumns = `chat, user, flow, node, vars, status, started_at, updated_at, expires_at`
+
+func scanFlowSession(row interface{ Scan(...interface{}) error }) (*flowSession, error) {
+	var s flowSession
+	var vars string
+	var started, updated, expires int64
+	if err := row.Scan(&s.Chat, &s.User, &s.Flow, &s.Node, &vars, &s.Status, &started, &updated, &expires); err != nil {
+		return nil, err
+	}
+	s.StartedAt, s.UpdatedAt, s.ExpiresAt = time.Unix(started, 0), time.Unix(updated, 0), time.Unix(expires, 0)
+	if err := json.Unmarshal([]byte(vars), &s.Vars); err != nil || s.Vars == nil {
+		s.Vars = map[string]string{}
+	}
+	return &s, nil
+}
+
+// loadFlowSession mengembalikan nil kalau tidak ada sesi yang masih berlaku.
+func loadFlowSession(chat, user string) (*flowSession, error) {
+	s, err := scanFlowSession(gwDB.QueryRow(`SELECT `+flowSessionColumns+`
+		FROM flow_sessions WHERE chat = ? AND user = ? AND expires_at > ?`, chat, user, time.Now().Unix()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return s, err
+}
+
+func saveFlowSession(s *flowSession) error {
+	s.UpdatedAt = time.Now()
+	vars, _ := json.Marshal(s.Vars)
+	_, err := gwDB.Exec(`INSERT INTO flow_sessions (`+flowSessionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (chat, user) DO UPDATE SET flow = excluded.flow, node = excluded.node,
+			vars = excluded.vars, status = excluded.status, started_at = excluded.started_at,
+			updated_at = excluded.updated_at, expires_at = excluded.expires_at`,
+		s.Chat, s.User, s.Flow, s.Node, string(vars), s.Status,
+		s.StartedAt.Unix(), s.UpdatedAt.Unix(), s.ExpiresAt.Unix())
+	return err
+}
+
+func deleteFlowSession(chat, user string) (bool, error) {
+	res, err := gwDB.Exec(`DELETE FROM flow_sessions WHERE chat = ? AND user = ?`, chat, user)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+/* ---------- Engine ---------- */
+
+type flowJob struct {
+	e          msgEvent
+	chat, user string   // kunci sesi, lihat flowKey
+	start      *flowDef // flow yang dimulai kalau belum ada sesi
+}
+
+// flowKey mengembalikan kunci sesi flow untuk pesan. Pengirim (dan chat
+// pribadi) yang dialamatkan lewat @lid diterjemahkan ke JID nomor telepon
+// supaya satu orang selalu dapat sesi yang sama dan bisa dicari dengan
+// nomornya di DELETE /flows/sessions/{jid}.
+func flowKey(e msgEvent) (chat, user string) {
+	user = e.Sender
+	if e.Phone != "" {
+		user = types.NewJID(e.Phone, types.DefaultUserServer).String()
+	}
+	if e.IsGroup {
+		return e.Chat, user
+	}
+	return user, user
+}
+
+// handleFlow mengembalikan true kalau pesan milik sesi flow (termasuk yang
+// sedang handoff) atau memulai flow baru. Pesan satu sesi diproses berurutan
+// supaya balasan beruntun tidak saling mendahului; sesi yang berbeda jalan
+// paralel dan event handler tidak pernah menunggu.
+func handleFlow(e msgEvent) bool {
+	if e.FromMe {
+		return false
+	}
+	flowsMu.RLock()
+	set := flows
+	flowsMu.RUnlock()
+	if len(set.Flows) == 0 {
+		return false
+	}
+	chat, user := flowKey(e)
+	// pesan sebelumnya masih diproses (mis. menunggu node webhook): balasan
+	// ini ikut antrean yang sama karena sesinya mungkin belum tersimpan
+	if flowPending(chat, user) {
+		enqueueFlow(flowJob{e: e, chat: chat, user: user, start: set.triggered(e)})
+		return true
+	}
+	sess, err := loadFlowSession(chat, user)
+	if err != nil {
+		return false
+	}
+	if sess != nil && set.byName(sess.Flow) != nil {
+		if sess.Status == flowActive {
+			enqueueFlow(flowJob{e: e, chat: chat, user: user})
+		}
+		return true
+	}
+	if f := set.triggered(e); f != nil {
+		enqueueFlow(flowJob{e: e, chat: chat, user: user, start: f})
+		return true
+	}
+	return false
+}
+
+func flowPending(chat, user string) bool {
+	flowQueuesMu.Lock()
+	defer flowQueuesMu.Unlock()
+	_, ok := flowQueues[chat+"\x00"+user]
+	return ok
+}
+
+// enqueueFlow tidak pernah blocking: kalau antrean sesi penuh, pesan
+// dibuang dan dilaporkan lewat flow.error.
+func enqueueFlow(job flowJob) {
+	key := job.chat + "\x00" + job.user
+	flowQueuesMu.Lock()
+	defer flowQueuesMu.Unlock()
+	q, ok := flowQueues[key]
+	if !ok {
+		q = make(chan flowJob, flowQueueSize)
+		flowQueues[key] = q
+		go runFlowQueue(key, q)
+	}
+	select {
+	case q <- job:
+	default:
+		emit("flow.error", time.Now(), map[string]interface{}{
+			"chat": job.chat, "user": job.user, "message_id": job.e.ID, "error": "too many pending messages for this session",
+		})
+	}
+}
+
+func runFlowQueue(key string, q chan flowJob) {
+	for {
+		select {
+		case job := <-q:
+			if err := processFlow(job); err != nil {
+				emit("flow.error", time.Now(), map[string]interface{}{
+					"chat": job.chat, "user": job.user, "error": err.Error(),
+				})
+			}
+		default:
+			// cek ulang di bawah lock supaya job yang baru masuk tidak tertinggal
+			flowQueuesMu.Lock()
+			if len(q) == 0 {
+				delete(flowQueues, key)
+				flowQueuesMu.Unlock()
+				return
+			}
+			flowQueuesMu.Unlock()
+		}
+	}
+}
+
+func processFlow(job flowJob) error {
+	e := job.e
+	flowsMu.RLock()
+	set := flows
+	flowsMu.RUnlock()
+
+	sess, err := loadFlowSession(job.chat, job.user)
+	if err != nil {
+		return err
+	}
+	if sess == nil {
+		if job.start == nil {
+			return nil // sesi sudah berakhir sebelum pesan ini diproses
+		}
+		return startFlow(job.start, job.chat, job.user, &e)
+	}
+	f := set.byName(sess.Flow)
+	if f == nil {
+		// flow-nya sudah dihapus lewat PUT /flows atau reload; pesan yang
+		// memicu flow lain tetap memulai sesi baru
+		if _, err := deleteFlowSession(sess.Chat, sess.User); err != nil {
+			return err
+		}
+		if job.start == nil {
+			return nil
+		}
+		return startFlow(job.start, job.chat, job.user, &e)
+	}
+	if sess.Status != flowActive {
+		return nil
+	}
+	for _, word := range f.Cancel {
+		if strings.EqualFold(strings.TrimSpace(e.Text), word) {
+			flowSend(sess.Chat, f.CancelMessage)
+			return endFlow(sess, "cancel")
+		}
+	}
+
+	n := f.Nodes[sess.Node]
+	if n == nil {
+		return endFlow(sess, "missing_node")
+	}
+	next, ok := n.accept(e.Text, sess.Vars)
+	if !ok {
+		flowSend(sess.Chat, n.invalidText(sess, &e))
+		sess.ExpiresAt = time.Now().Add(f.timeout)
+		return saveFlowSession(sess)
+	}
+	return runFlow(f, sess, next, &e)
+}
+
+func startFlow(f *flowDef, chat, user string, e *msgEvent) error {
+	now := time.Now()
+	sess := &flowSession{Chat: chat, User: user, Flow: f.Name, Vars: map[string]string{},
+		Status: flowActive, StartedAt: now}
+	emit("flow.start", now, map[string]interface{}{"flow": f.Name, "chat": sess.Chat, "user": sess.User})
+	return runFlow(f, sess, f.Start, e)
+}
+
+// accept memproses balasan pengguna di node choice/input.
+func (n *flowNode) accept(text string, vars map[string]string) (next string, ok bool) {
+	text = strings.TrimSpace(text)
+	switch n.Type {
+	case "choice":
+		for _, o := range n.Options {
+			if strings.EqualFold(text, o.Key) || (o.Label != "" && strings.EqualFold(text, o.Label)) {
+				if n.Var != "" {
+					vars[n.Var] = o.Key
+				}
+				return o.Next, true
+			}
+		}
+		return "", false
+	case "input":
+		if text == "" || (n.re != nil && !n.re.MatchString(text)) {
+			return "", false
+		}
+		vars[n.Var] = text
+		return n.Next, true
+	}
+	return n.Next, true
+}
+
+// runFlow menjalankan node mulai dari name sampai ketemu node yang menunggu
+// balasan (choice, input), handoff, atau flow selesai.
+func runFlow(f *flowDef, sess *flowSession, name string, e *msgEvent) error {
+	for step := 0; step < maxFlowSteps; step++ {
+		if name == "" {
+			return endFlow(sess, "done")
+		}
+		n := f.Nodes[name]
+		sess.Node = name
+		switch n.Type {
+		case "message":
+			flowSend(sess.Chat, renderFlow(n.tmpl, sess, e))
+			name = n.Next
+		case "choice", "input":
+			// simpan dulu sebelum prompt terkirim: balasan cepat dicocokkan
+			// handleFlow lewat tabel flow_sessions
+			sess.ExpiresAt = time.Now().Add(f.timeout)
+			if err := saveFlowSession(sess); err != nil {
+				return err
+			}
+			flowSend(sess.Chat, n.prompt(sess, e))
+			return nil
+		case "branch":
+			name = n.branch(sess.Vars)
+		case "webhook":
+			next, err := callFlowWebhook(f, n, sess, e)
+			if err != nil {
+				emit("flow.error", time.Now(), map[string]interface{}{
+					"flow": f.Name, "node": sess.Node, "chat": sess.Chat, "user": sess.User, "error": err.Error(),
+				})
+				if n.OnError == "" {
+					return endFlow(sess, "webhook_error")
+				}
+				next = n.OnError
+			}
+			name = next
+		case "handoff":
+			flowSend(sess.Chat, renderFlow(n.tmpl, sess, e))
+			sess.Status = flowHandoff
+			sess.ExpiresAt = time.Now().Add(f.handoffTimeout)
+			if err := saveFlowSession(sess); err != nil {
+				return err
+			}
+			emit("flow.handoff", time.Now(), sess)
+			return nil
+		case "end":
+			flowSend(sess.Chat, renderFlow(n.tmpl, sess, e))
+			return endFlow(sess, "done")
+		}
+	}
+	return endFlow(sess, "too_many_steps")
+}
+
+func (n *flowNode) prompt(sess *flowSession, e *msgEvent) string {
+	if n.tmpl != nil || n.Type != "choice" {
+		return renderFlow(n.tmpl, sess, e)
+	}
+	// choice tanpa teks: tampilkan daftar pilihan apa adanya
+	lines := make([]string, len(n.Options))
+	for i, o := range n.Options {
+		lines[i] = o.Key
+		if o.Label != "" {
+			lines[i] += ". " + o.Label
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (n *flowNode) invalidText(sess *flowSession, e *msgEvent) string {
+	if n.invalid != nil {
+		return renderFlow(n.invalid, sess, e)
+	}
+	if n.Type == "choice" {
+		keys := make([]string, len(n.Options))
+		for i, o := range n.Options {
+			keys[i] = o.Key
+		}
+		return "Pilihan tidak dikenal. Balas dengan salah satu: " + strings.Join(keys, ", ")
+	}
+	return "Format tidak sesuai, silakan coba lagi."
+}
+
+func (n *flowNode) branch(vars map[string]string) string {
+	for _, b := range n.Branches {
+		v := vars[b.Var]
+		switch {
+		case b.Equals != "" && strings.EqualFold(v, b.Equals),
+			b.re != nil && b.re.MatchString(v),
+			b.Equals == "" && b.re == nil && v != "":
+			return b.Next
+		}
+	}
+	return n.Default
+}
+
+// callFlowWebhook mengirim {"flow","node","chat","user","vars","message"}
+// dan menerima {"vars":{...},"reply":"...","next":"node"}; semuanya opsional.
+func callFlowWebhook(f *flowDef, n *flowNode, sess *flowSession, e *msgEvent) (string, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"flow": f.Name, "node": sess.Node, "chat": sess.Chat, "user": sess.User, "vars": sess.Vars, "message": e,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("flow webhook returned %s", resp.Status)
+	}
+	var out struct {
+		Vars  map[string]string `json:"vars"`
+		Reply string            `json:"reply"`
+		Next  string            `json:"next"`
+	}
+	if raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20)); len(bytes.TrimSpace(raw)) > 0 {
+		if err := json.Unmarshal(raw, &out); err != nil {
+			return "", fmt.Errorf("flow webhook: bad json: %v", err)
+		}
+	}
+	for k, v := range out.Vars {
+		sess.Vars[k] = v
+	}
+	flowSend(sess.Chat, out.Reply)
+	if out.Next != "" {
+		if f.Nodes[out.Next] == nil {
+			return "", fmt.Errorf("flow webhook: unknown next node %q", out.Next)
+		}
+		return out.Next, nil
+	}
+	return n.Next, nil
+}
+
+func endFlow(sess *flowSession, reason string) error {
+	if _, err := deleteFlowSession(sess.Chat, sess.User); err != nil {
+		return err
+	}
+	emit("flow.end", time.Now(), map[string]interface{}{
+		"flow": sess.Flow, "chat": sess.Chat, "user": sess.User, "node": sess.Node, "reason": reason, "vars": sess.Vars,
+	})
+	return nil
+}
+
+// flowData adalah data template teks node, mis. {{.Name}} atau {{.Vars.invoice}}.
+type flowData struct {
+	Name   string
+	Number string
+	Text   string
+	Chat   string
+	Vars   map[string]string
+}
+
+func renderFlow(t *template.Template, sess *flowSession, e *msgEvent) string {
+	if t == nil {
+		return ""
+	}
+	data := flowData{Chat: sess.Chat, Vars: sess.Vars}
+	if jid, err := types.ParseJID(sess.User); err == nil {
+		data.Number = jid.User
+	}
+	if e != nil {
+		data.Name, data.Text = e.PushName, e.Text
+	}
+	if data.Name == "" {
+		data.Name = data.Number
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+func flowSend(chat, text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	jid, err := types.ParseJID(chat)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, _ = sendMessage(ctx, jid, &waProto.Message{Conversation: proto.String(text)})
+}
+
+// expireFlowSessions menghapus sesi yang lewat timeout tiap menit dan
+// mengirim timeout_message ke sesi yang masih aktif (bukan handoff).
+func expireFlowSessions() {
+	for range time.Tick(flowJanitorTick) {
+		rows, err := gwDB.Query(`SELECT `+flowSessionColumns+` FROM flow_sessions WHERE expires_at <= ?`, time.Now().Unix())
+		if err != nil {
+			continue
+		}
+		var expired []*flowSession
+		for rows.Next() {
+			if s, err := scanFlowSession(rows); err == nil {
+				expired = append(expired, s)
+			}
+		}
+		rows.Close()
+
+		flowsMu.RLock()
+		set := flows
+		flowsMu.RUnlock()
+		for _, s := range expired {
+			// dicek ulang karena sesi bisa saja diperpanjang sejak query di atas
+			res, err := gwDB.Exec(`DELETE FROM flow_sessions WHERE chat = ? AND user = ? AND expires_at <= ?`,
+				s.Chat, s.User, time.Now().Unix())
+			if err != nil {
+				continue
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				continue
+			}
+			if f := set.byName(s.Flow); f != nil && s.Status == flowActive {
+				flowSend(s.Chat, f.TimeoutMessage)
+			}
+			emit("flow.timeout", time.Now(), s)
+		}
+	}
+}
+
+/* ---------- Flows API ---------- */
+
+func getFlowsHandler(w http.ResponseWriter, r *http.Request) {
+	flowsMu.RLock()
+	set := flows
+	flowsMu.RUnlock()
+	if set.Flows == nil {
+		set.Flows = []*flowDef{}
+	}
+	writeJSON(w, map[string]interface{}{"flows": set.Flows, "file": os.Getenv("FLOWS_FILE")})
+}
+
+// putFlowsHandler menerima {"flows":[...]} dalam JSON, atau YAML kalau
+// Content-Type berisi "yaml". Sesi yang sedang berjalan tetap lanjut selama
+// nama flow-nya masih ada.
+func putFlowsHandler(w http.ResponseWriter, r *http.Request) {
+	if os.Getenv("FLOWS_FILE") != "" {
+		writeError(w, 409, "flows are managed by FLOWS_FILE; edit the file and POST /flows/reload")
+		return
+	}
+	var set flowSet
+	raw, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err == nil {
+		if strings.Contains(r.Header.Get("Content-Type"), "yaml") {
+			err = yaml.Unmarshal(raw, &set)
+		} else {
+			err = json.Unmarshal(raw, &set)
+		}
+	}
+	if err != nil {
+		writeError(w, 400, "bad flows document: "+err.Error())
+		return
+	}
+	if err := compileFlows(&set); err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	if err := saveSetting(flowsKey, set); err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	flowsMu.Lock()
+	flows = set
+	flowsMu.Unlock()
+	writeJSON(w, map[string]interface{}{"status": "saved", "total": len(set.Flows)})
+}
+
+func reloadFlowsHandler(w http.ResponseWriter, r *http.Request) {
+	if err := loadFlows(); err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	flowsMu.RLock()
+	total := len(flows.Flows)
+	flowsMu.RUnlock()
+	writeJSON(w, map[string]interface{}{"status": "reloaded", "total": total})
+}
+
+// listFlowSessionsHandler: GET /flows/sessions?status=handoff.
+func listFlowSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	query := `SELECT ` + flowSessionColumns + ` FROM flow_sessions WHERE expires_at > ?`
+	args := []interface{}{time.Now().Unix()}
+	if st := r.URL.Query().Get("status"); st != "" {
+		query += ` AND status = ?`
+		args = append(args, st)
+	}
+	rows, err := gwDB.Query(query+` ORDER BY updated_at DESC`, args...)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	defer rows.Close()
+	out := []*flowSession{}
+	for rows.Next() {
+		s, err := scanFlowSession(rows)
+		if err != nil {
+			writeError(w, 500, err.Error())
+			return
+		}
+		out = append(out, s)
+	}
+	writeJSON(w, map[string]interface{}{"sessions": out})
+}
+
+// endFlowSessionHandler mengakhiri sesi, termasuk melepas handoff setelah
+// agen selesai. Untuk grup, isi ?user= dengan nomor peserta. JID @lid
+// diterjemahkan ke nomor seperti kunci sesi di flowKey.
+func endFlowSessionHandler(w http.ResponseWriter, r *http.Request) {
+	chat, err := phone.NormalizeJID(r.PathValue("jid"))
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	chat, _ = phoneJID(chat, types.EmptyJID)
+	user := chat
+	if u := r.URL.Query().Get("user"); u != "" {
+		if user, err = phone.NormalizeJID(u); err != nil {
+			writeError(w, 400, err.Error())
+			return
+		}
+		user, _ = phoneJID(user, types.EmptyJID)
+	}
+	sess, err := loadFlowSession(chat.String(), user.String())
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	if sess == nil {
+		writeError(w, 404, "no active flow session")
+		return
+	}
+	if err := endFlow(sess, "released"); err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]interface{}{"status": "ended", "flow": sess.Flow, "node": sess.Node})
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDurationOr(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "", want: 30 * time.Minute},
+		{in: "10m", want: 10 * time.Minute},
+		{in: "1h30m", want: 90 * time.Minute},
+		{in: "0s", wantErr: true},
+		{in: "-5m", wantErr: true},
+		{in: "30", wantErr: true},
+		{in: "besok", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := durationOr(tt.in, 30*time.Minute)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("durationOr(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("durationOr(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestFlowKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		e          msgEvent
+		chat, user string
+	}{
+		{
+			name: "pribadi",
+			e:    msgEvent{Chat: "628123456789@s.whatsapp.net", Sender: "628123456789@s.whatsapp.net", Phone: "628123456789"},
+			chat: "628123456789@s.whatsapp.net", user: "628123456789@s.whatsapp.net",
+		},
+		{
+			name: "pribadi lewat lid",
+			e:    msgEvent{Chat: "123456789012345@lid", Sender: "123456789012345@lid", Phone: "628123456789"},
+			chat: "628123456789@s.whatsapp.net", user: "628123456789@s.whatsapp.net",
+		},
+		{
+			name: "lid tanpa nomor",
+			e:    msgEvent{Chat: "123456789012345@lid", Sender: "123456789012345@lid"},
+			chat: "123456789012345@lid", user: "123456789012345@lid",
+		},
+		{
+			name: "grup",
+			e:    msgEvent{Chat: "120363000000000000@g.us", Sender: "123456789012345@lid", Phone: "628123456789", IsGroup: true},
+			chat: "120363000000000000@g.us", user: "628123456789@s.whatsapp.net",
+		},
+	}
+	for _, tt := range tests {
+		chat, user := flowKey(tt.e)
+		if chat != tt.chat || user != tt.user {
+			t.Errorf("%s: flowKey = %q, %q; want %q, %q", tt.name, chat, user, tt.chat, tt.user)
+		}
+	}
+}
+
+func TestFlowNodeAccept(t *testing.T) {
+	set := flowSet{Flows: []*flowDef{{
+		Name:  "cs",
+		Start: "menu",
+		Nodes: map[string]*flowNode{
+			"menu": {Type: "choice", Var: "menu", Options: []flowOption{
+				{Key: "1", Label: "Tagihan", Next: "invoice"},
+				{Key: "2", Next: "agent"},
+			}},
+			"invoice": {Type: "input", Var: "invoice", Validate: `^\d{6}$`, Next: "agent"},
+			"nama":    {Type: "input", Var: "nama", Next: "agent"},
+			"agent":   {Type: "handoff"},
+		},
+	}}}
+	if err := compileFlows(&set); err != nil {
+		t.Fatal(err)
+	}
+	nodes := set.Flows[0].Nodes
+	tests := []struct {
+		node, text string
+		next       string
+		ok         bool
+		vars       map[string]string
+	}{
+		{node: "menu", text: "1", next: "invoice", ok: true, vars: map[string]string{"menu": "1"}},
+		{node: "menu", text: " 2 ", next: "agent", ok: true, vars: map[string]string{"menu": "2"}},
+		{node: "menu", text: "tagihan", next: "invoice", ok: true, vars: map[string]string{"menu": "1"}},
+		{node: "menu", text: "3"},
+		{node: "menu", text: ""},
+		{node: "invoice", text: "123456", next: "agent", ok: true, vars: map[string]string{"invoice": "123456"}},
+		{node: "invoice", text: "12345"},
+		{node: "invoice", text: "abcdef"},
+		{node: "nama", text: "Budi", next: "agent", ok: true, vars: map[string]string{"nama": "Budi"}},
+		{node: "nama", text: "   "},
+		{node: "agent", text: "apa saja", ok: true},
+	}
+	for _, tt := range tests {
+		vars := map[string]string{}
+		next, ok := nodes[tt.node].accept(tt.text, vars)
+		if next != tt.next || ok != tt.ok {
+			t.Errorf("%s.accept(%q) = %q, %v; want %q, %v", tt.node, tt.text, next, ok, tt.next, tt.ok)
+		}
+		for k, v := range tt.vars {
+			if vars[k] != v {
+				t.Errorf("%s.accept(%q): vars[%q] = %q, want %q", tt.node, tt.text, k, vars[k], v)
+			}
+		}
+		if !tt.ok && len(vars) != 0 {
+			t.Errorf("%s.accept(%q) set vars %v on invalid input", tt.node, tt.text, vars)
+		}
+	}
+}
+
+func TestFlowNodeBranch(t *testing.T) {
+	set := flowSet{Flows: []*flowDef{{
+		Name:  "cek",
+		Start: "cabang",
+		Nodes: map[string]*flowNode{
+			"cabang": {Type: "branch", Default: "lain", Branches: []flowBranch{
+				{Var: "status", Equals: "LUNAS", Next: "lunas"},
+				{Var: "invoice", Regex: `^9`, Next: "khusus"},
+				{Var: "catatan", Next: "catatan"},
+			}},
+			"lunas":   {Type: "end"},
+			"khusus":  {Type: "end"},
+			"catatan": {Type: "end"},
+			"lain":    {Type: "end"},
+		},
+	}}}
+	if err := compileFlows(&set); err != nil {
+		t.Fatal(err)
+	}
+	n := set.Flows[0].Nodes["cabang"]
+	tests := []struct {
+		vars map[string]string
+		want string
+	}{
+		{vars: map[string]string{"status": "lunas"}, want: "lunas"},
+		{vars: map[string]string{"status": "belum", "invoice": "912345"}, want: "khusus"},
+		{vars: map[string]string{"invoice": "123456"}, want: "lain"},
+		{vars: map[string]string{"catatan": "segera"}, want: "catatan"},
+		{vars: map[string]string{"catatan": ""}, want: "lain"},
+		{vars: map[string]string{"status": "lunas", "invoice": "912345"}, want: "lunas"}, // urutan menentukan
+		{vars: map[string]string{}, want: "lain"},
+	}
+	for _, tt := range tests {
+		if got := n.branch(tt.vars); got != tt.want {
+			t.Errorf("branch(%v) = %q, want %q", tt.vars, got, tt.want)
+		}
+	}
+}
+
+func TestCompileFlows(t *testing.T) {
+	end := func() map[string]*flowNode { return map[string]*flowNode{"a": {Type: "end"}} }
+	tests := []struct {
+		name    string
+		flows   []*flowDef
+		wantErr string
+	}{
+		{name: "valid", flows: []*flowDef{{Name: "ok", Start: "a", Nodes: end()}}},
+		{name: "tanpa nama", flows: []*flowDef{{Start: "a", Nodes: end()}}, wantErr: "needs a name"},
+		{name: "nil", flows: []*flowDef{nil}, wantErr: "needs a name"},
+		{
+			name:    "duplikat",
+			flows:   []*flowDef{{Name: "x", Start: "a", Nodes: end()}, {Name: "x", Start: "a", Nodes: end()}},
+			wantErr: "duplicate flow",
+		},
+		{name: "start hilang", flows: []*flowDef{{Name: "x", Start: "b", Nodes: end()}}, wantErr: "start node"},
+		{name: "timeout salah", flows: []*flowDef{{Name: "x", Start: "a", Timeout: "lama", Nodes: end()}}, wantErr: "timeout"},
+		{
+			name:    "handoff_timeout salah",
+			flows:   []*flowDef{{Name: "x", Start: "a", HandoffTimeout: "-1h", Nodes: end()}},
+			wantErr: "handoff_timeout",
+		},
+		{
+			name:    "trigger salah",
+			flows:   []*flowDef{{Name: "x", Start: "a", Trigger: ruleMatch{ChatType: "channel"}, Nodes: end()}},
+			wantErr: "trigger",
+		},
+		{
+			name:    "tipe node",
+			flows:   []*flowDef{{Name: "x", Start: "a", Nodes: map[string]*flowNode{"a": {Type: "menu"}}}},
+			wantErr: "type must be",
+		},
+		{
+			name:    "next tidak ada",
+			flows:   []*flowDef{{Name: "x", Start: "a", Nodes: map[string]*flowNode{"a": {Type: "message", Next: "b"}}}},
+			wantErr: "unknown node",
+		},
+		{
+			name:    "choice tanpa opsi",
+			flows:   []*flowDef{{Name: "x", Start: "a", Nodes: map[string]*flowNode{"a": {Type: "choice"}}}},
+			wantErr: "choice needs options",
+		},
+		{
+			name: "opsi tanpa key",
+			flows: []*flowDef{{Name: "x", Start: "a", Nodes: map[string]*flowNode{
+				"a": {Type: "choice", Options: []flowOption{{Next: "a"}}},
+			}}},
+			wantErr: "needs a key",
+		},
+		{
+			name:    "input tanpa var",
+			flows:   []*flowDef{{Name: "x", Start: "a", Nodes: map[string]*flowNode{"a": {Type: "input"}}}},
+			wantErr: "input needs var",
+		},
+		{
+			name:    "webhook tanpa url",
+			flows:   []*flowDef{{Name: "x", Start: "a", Nodes: map[string]*flowNode{"a": {Type: "webhook"}}}},
+			wantErr: "webhook needs url",
+		},
+		{
+			name: "branch tanpa var",
+			flows: []*flowDef{{Name: "x", Start: "a", Nodes: map[string]*flowNode{
+				"a": {Type: "branch", Branches: []flowBranch{{Next: "a"}}},
+			}}},
+			wantErr: "needs var",
+		},
+		{
+			name: "regex branch salah",
+			flows: []*flowDef{{Name: "x", Start: "a", Nodes: map[string]*flowNode{
+				"a": {Type: "branch", Branches: []flowBranch{{Var: "v", Regex: "(", Next: "a"}}},
+			}}},
+			wantErr: "bad regex",
+		},
+		{
+			name: "validate salah",
+			flows: []*flowDef{{Name: "x", Start: "a", Nodes: map[string]*flowNode{
+				"a": {Type: "input", Var: "v", Validate: "["},
+			}}},
+			wantErr: "bad validate regex",
+		},
+		{
+			name:    "template salah",
+			flows:   []*flowDef{{Name: "x", Start: "a", Nodes: map[string]*flowNode{"a": {Type: "end", Text: "{{.Name"}}}},
+			wantErr: "template",
+		},
+	}
+	for _, tt := range tests {
+		err := compileFlows(&flowSet{Flows: tt.flows})
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
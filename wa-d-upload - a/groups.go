@@ -0,0 +1,407 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+/* ---------- Groups ---------- */
+
+type groupSummary struct {
+	JID          string    `json:"jid"`
+	Name         string    `json:"name"`
+	Topic        string    `json:"topic,omitempty"`
+	Participants int       `json:"participants"`
+	IsAnnounce   bool      `json:"is_announce"`
+	IsLocked     bool      `json:"is_locked"`
+	Created      time.Time `json:"created"`
+}
+
+func registerGroupRoutes() {
+	http.HandleFunc("GET /groups", listGroupsHandler)
+	http.HandleFunc("POST /groups", createGroupHandler)
+	http.HandleFunc("POST /groups/join", joinGroupHandler)
+	http.HandleFunc("GET /groups/{jid}", groupInfoHandler)
+	http.HandleFunc("POST /groups/{jid}/participants", groupParticipantsHandler)
+	http.HandleFunc("POST /groups/{jid}/subject", groupSubjectHandler)
+	http.HandleFunc("POST /groups/{jid}/description", groupDescriptionHandler)
+	http.HandleFunc("POST /groups/{jid}/picture", groupPictureHandler)
+	http.HandleFunc("GET /groups/{jid}/invite", groupInviteHandler)
+	http.HandleFunc("POST /groups/{jid}/invite/revoke", groupRevokeInviteHandler)
+	http.HandleFunc("POST /groups/{jid}/leave", leaveGroupHandler)
+}
+
+func listGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireLogin(w) {
+		return
+	}
+	groups, err := cli.GetJoinedGroups()
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	out := make([]groupSummary, 0, len(groups))
+	for _, g := range groups {
+		out = append(out, groupSummary{
+			JID:          g.JID.String(),
+			Name:         g.Name,
+			Topic:        g.Topic,
+			Participants: len(g.Participants),
+			IsAnnounce:   g.IsAnnounce,
+			IsLocked:     g.IsLocked,
+			Created:      g.GroupCreated,
+		})
+	}
+	writeJSON(w, map[string]interface{}{"groups": out, "total": len(out)})
+}
+
+func createGroupHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireLogin(w) {
+		return
+	}
+	var body struct {
+		Name         string   `json:"name"`
+		Participants []string `json:"participants"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" {
+		writeError(w, 400, "bad json")
+		return
+	}
+	participants, err := parseUserJIDs(body.Participants)
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	info, err := cli.CreateGroup(whatsmeow.ReqCreateGroup{Name: body.Name, Participants: participants})
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, info)
+}
+
+func joinGroupHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireLogin(w) {
+		return
+	}
+	var body struct {
+		Link string `json:"link"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Link == "" {
+		writeError(w, 400, "bad json")
+		return
+	}
+	code, err := inviteCode(body.Link)
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	jid, err := cli.JoinGroupWithLink(code)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"status": "joined", "jid": jid.String()})
+}
+
+// inviteCode mengambil kode undangan dari link chat.whatsapp.com (dengan
+// atau tanpa skema, www. dan query) atau kode polosnya.
+func inviteCode(link string) (string, error) {
+	link = strings.TrimSpace(link)
+	if !strings.Contains(link, "/") {
+		if link == "" {
+			return "", errors.New("invite link is empty")
+		}
+		return link, nil
+	}
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", errors.New("invalid invite link")
+	}
+	if host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www."); host != "chat.whatsapp.com" {
+		return "", errors.New("invite link must point to chat.whatsapp.com")
+	}
+	code := path.Base(strings.TrimRight(u.Path, "/"))
+	if code == "." || code == "/" {
+		return "", errors.New("invite link has no code")
+	}
+	return code, nil
+}
+
+func groupInfoHandler(w http.ResponseWriter, r *http.Request) {
+	jid, ok := groupFromPath(w, r)
+	if !ok {
+		return
+	}
+	info, err := cli.GetGroupInfo(jid)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, info)
+}
+
+func groupParticipantsHandler(w http.ResponseWriter, r *http.Request) {
+	jid, ok := groupFromPath(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Action       string   `json:"action"` // add, remove, promote, demote
+		Participants []string `json:"participants"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Participants) == 0 {
+		writeError(w, 400, "bad json")
+		return
+	}
+	action := whatsmeow.ParticipantChange(body.Action)
+	switch action {
+	case whatsmeow.ParticipantChangeAdd, whatsmeow.ParticipantChangeRemove,
+		whatsmeow.ParticipantChangePromote, whatsmeow.ParticipantChangeDemote:
+	default:
+		writeError(w, 400, "action must be add, remove, promote or demote")
+		return
+	}
+	participants, err := parseUserJIDs(body.Participants)
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	result, err := cli.UpdateGroupParticipants(jid, participants, action)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]interface{}{"action": action, "participants": result})
+}
+
+func groupSubjectHandler(w http.ResponseWriter, r *http.Request) {
+	jid, ok := groupFromPath(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Subject string `json:"subject"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Subject == "" {
+		writeError(w, 400, "bad json")
+		return
+	}
+	if err := cli.SetGroupName(jid, body.Subject); err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"status": "updated", "subject": body.Subject})
+}
+
+func groupDescriptionHandler(w http.ResponseWriter, r *http.Request) {
+	jid, ok := groupFromPath(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, 400, "bad json")
+		return
+	}
+	if err := cli.SetGroupDescription(jid, body.Description); err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"status": "updated", "description": body.Description})
+}
+
+// groupPictureHandler menerima {"image":"<base64 JPEG>"}; image kosong
+// menghapus foto grup.
+func groupPictureHandler(w http.ResponseWriter, r *http.Request) {
+	jid, ok := groupFromPath(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Image string `json:"image"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, 400, "bad json")
+		return
+	}
+	var avatar []byte
+	if body.Image != "" {
+		var err error
+		if avatar, err = base64.StdEncoding.DecodeString(body.Image); err != nil {
+			writeError(w, 400, "image must be base64")
+			return
+		}
+	}
+	pictureID, err := cli.SetGroupPhoto(jid, avatar)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"status": "updated", "picture_id": pictureID})
+}
+
+func groupInviteHandler(w http.ResponseWriter, r *http.Request) {
+	jid, ok := groupFromPath(w, r)
+	if !ok {
+		return
+	}
+	link, err := cli.GetGroupInviteLink(jid, false)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"link": link})
+}
+
+func groupRevokeInviteHandler(w http.ResponseWriter, r *http.Request) {
+	jid, ok := groupFromPath(w, r)
+	if !ok {
+		return
+	}
+	link, err := cli.GetGroupInviteLink(jid, true)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"status": "revoked", "link": link})
+}
+
+func leaveGroupHandler(w http.ResponseWriter, r *http.Request) {
+	jid, ok := groupFromPath(w, r)
+	if !ok {
+		return
+	}
+	if err := cli.LeaveGroup(jid); err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"status": "left", "jid": jid.String()})
+}
+
+// groupFromPath mengambil {jid} dari URL; ID grup tanpa "@g.us" juga diterima.
+func groupFromPath(w http.ResponseWriter, r *http.Request) (types.JID, bool) {
+	if !requireLogin(w) {
+		return types.EmptyJID, false
+	}
+	raw := r.PathValue("jid")
+	if !strings.Contains(raw, "@") {
+		raw += "@" + types.GroupServer
+	}
+	jid, err := types.ParseJID(raw)
+	if err != nil || jid.Server != types.GroupServer {
+		writeError(w, 400, "invalid group JID")
+		return types.EmptyJID, false
+	}
+	return jid, true
+}
+
+/* ---------- Group events ---------- */
+
+type groupParticipantsEvent struct {
+	Group        string      `json:"group"`
+	Actor        string      `json:"actor,omitempty"`
+	Action       string      `json:"action"` // join, leave, promote, demote
+	Reason       string      `json:"reason,omitempty"`
+	Participants []types.JID `json:"participants"`
+}
+
+type groupChangeEvent struct {
+	Group string      `json:"group"`
+	Actor string      `json:"actor,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+// handleGroupInfo memecah satu events.GroupInfo menjadi event bertipe. Satu
+// notifikasi dari server bisa memuat beberapa perubahan sekaligus.
+func handleGroupInfo(v *events.GroupInfo) {
+	group := v.JID.String()
+	actor := jidPtrString(v.Sender)
+	participants := func(action string, list []types.JID) {
+		if len(list) == 0 {
+			return
+		}
+		emit("group.participants", v.Timestamp, groupParticipantsEvent{
+			Group: group, Actor: actor, Action: action, Reason: v.JoinReason, Participants: list,
+		})
+	}
+	participants("join", v.Join)
+	participants("leave", v.Leave)
+	participants("promote", v.Promote)
+	participants("demote", v.Demote)
+
+	change := func(typ string, value interface{}) {
+		emit(typ, v.Timestamp, groupChangeEvent{Group: group, Actor: actor, Value: value})
+	}
+	if v.Name != nil {
+		change("group.subject", v.Name.Name)
+	}
+	if v.Topic != nil {
+		change("group.description", v.Topic.Topic)
+	}
+	if v.Locked != nil {
+		change("group.settings", map[string]bool{"locked": v.Locked.IsLocked})
+	}
+	if v.Announce != nil {
+		change("group.settings", map[string]bool{"announce": v.Announce.IsAnnounce})
+	}
+	if v.Ephemeral != nil {
+		change("group.settings", map[string]interface{}{"ephemeral": v.Ephemeral.IsEphemeral, "disappearing_timer": v.Ephemeral.DisappearingTimer})
+	}
+	if v.MembershipApprovalMode != nil {
+		change("group.settings", map[string]bool{"join_approval_required": v.MembershipApprovalMode.IsJoinApprovalRequired})
+	}
+	if v.NewInviteLink != nil {
+		change("group.invite_link", *v.NewInviteLink)
+	}
+	if v.Delete != nil {
+		change("group.deleted", v.Delete.DeleteReason)
+	}
+}
+
+func handleJoinedGroup(v *events.JoinedGroup) {
+	// JoinedGroup tidak membawa waktu bergabung; GroupCreated adalah waktu
+	// grup dibuat
+	emit("group.joined", time.Now(), map[string]interface{}{
+		"group":  v.JID.String(),
+		"actor":  jidPtrString(v.Sender),
+		"reason": v.Reason,
+		"type":   v.Type,
+		"info":   v.GroupInfo,
+	})
+}
+
+func handlePicture(v *events.Picture) {
+	typ := "picture.changed"
+	if v.Remove {
+		typ = "picture.removed"
+	}
+	emit(typ, v.Timestamp, map[string]interface{}{
+		"jid":        v.JID.String(),
+		"actor":      v.Author.String(),
+		"is_group":   v.JID.Server == types.GroupServer,
+		"picture_id": v.PictureID,
+	})
+}
+
+func jidPtrString(j *types.JID) string {
+	if j == nil {
+		return ""
+	}
+	return j.String()
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestInviteCode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "https://chat.whatsapp.com/AbCdEf123456", want: "AbCdEf123456"},
+		{in: "http://chat.whatsapp.com/AbCdEf123456", want: "AbCdEf123456"},
+		{in: "https://www.chat.whatsapp.com/AbCdEf123456", want: "AbCdEf123456"},
+		{in: "chat.whatsapp.com/AbCdEf123456", want: "AbCdEf123456"},
+		{in: "https://chat.whatsapp.com/AbCdEf123456?mode=ac_t", want: "AbCdEf123456"},
+		{in: "https://chat.whatsapp.com/invite/AbCdEf123456/", want: "AbCdEf123456"},
+		{in: "https://chat.whatsapp.com/AbCdEf123456#x", want: "AbCdEf123456"},
+		{in: "  AbCdEf123456 ", want: "AbCdEf123456"},
+		{in: "", wantErr: true},
+		{in: "https://chat.whatsapp.com/", wantErr: true},
+		{in: "https://evil.example/AbCdEf123456", wantErr: true},
+		{in: "ftp://chat.whatsapp.com/AbCdEf123456", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := inviteCode(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("inviteCode(%q) = %q, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("inviteCode(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
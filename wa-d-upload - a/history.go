@@ -0,0 +1,163 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+/* ---------- History sync ---------- */
+
+// historyStatus merangkum import history sync sejak gateway jalan. Setelah
+// pairing pertama WhatsApp mengirim beberapa chunk; progress (0-100) diambil
+// dari chunk terakhir.
+type historyStatus struct {
+	Running       bool      `json:"running"`
+	SyncType      string    `json:"sync_type,omitempty"`
+	Chunks        int       `json:"chunks"`
+	Progress      uint32    `json:"progress"`
+	Conversations int       `json:"conversations"`
+	Imported      int       `json:"imported"`
+	Skipped       int       `json:"skipped"`
+	Errors        int       `json:"errors"`
+	LastError     string    `json:"last_error,omitempty"`
+	LastChunkAt   time.Time `json:"last_chunk_at,omitempty"`
+}
+
+var (
+	historyMu   sync.Mutex // satu chunk diproses sekaligus
+	historyStMu sync.Mutex
+	history     historyStatus
+)
+
+func registerHistoryRoutes() {
+	http.HandleFunc("GET /history/status", historyStatusHandler)
+}
+
+func historyStatusHandler(w http.ResponseWriter, r *http.Request) {
+	historyStMu.Lock()
+	st := history
+	historyStMu.Unlock()
+	writeJSON(w, map[string]interface{}{"archive_enabled": archiveEnabled, "status": st})
+}
+
+// handleHistorySync dijalankan di goroutine supaya chunk besar tidak menahan
+// event lain. Chunk push name dan status lama dilewati.
+func handleHistorySync(v *events.HistorySync) {
+	data := v.Data
+	switch data.GetSyncType() {
+	case waHistorySync.HistorySync_INITIAL_BOOTSTRAP, waHistorySync.HistorySync_RECENT,
+		waHistorySync.HistorySync_FULL, waHistorySync.HistorySync_ON_DEMAND:
+	default:
+		return
+	}
+	if !archiveEnabled {
+		return
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	updateHistory(func(st *historyStatus) {
+		st.Running = true
+		st.SyncType = data.GetSyncType().String()
+	})
+
+	imported, skipped, convs, err := importHistoryChunk(data)
+
+	updateHistory(func(st *historyStatus) {
+		st.Running = false
+		st.Chunks++
+		st.Progress = data.GetProgress()
+		st.Conversations += convs
+		st.Imported += imported
+		st.Skipped += skipped
+		st.LastChunkAt = time.Now()
+		if err != nil {
+			st.Errors++
+			st.LastError = err.Error()
+		}
+	})
+
+	historyStMu.Lock()
+	st := history
+	historyStMu.Unlock()
+	emit("history.progress", time.Now(), map[string]interface{}{
+		"sync_type":     st.SyncType,
+		"chunk_order":   data.GetChunkOrder(),
+		"progress":      st.Progress,
+		"conversations": convs,
+		"imported":      imported,
+		"skipped":       skipped,
+		"total":         st.Imported,
+	})
+}
+
+func updateHistory(fn func(*historyStatus)) {
+	historyStMu.Lock()
+	fn(&history)
+	historyStMu.Unlock()
+}
+
+// importHistoryChunk menyimpan semua percakapan dalam satu chunk di satu
+// transaksi. Reaksi, protocol message dan pesan yang sudah ada dihitung skipped.
+func importHistoryChunk(data *waHistorySync.HistorySync) (imported, skipped, convs int, err error) {
+	pushNames := make(map[string]string, len(data.GetPushnames()))
+	for _, p := range data.GetPushnames() {
+		pushNames[p.GetID()] = p.GetPushname()
+	}
+
+	tx, err := gwDB.Begin()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer tx.Rollback()
+
+	for _, conv := range data.GetConversations() {
+		chat, perr := types.ParseJID(conv.GetID())
+		if perr != nil || chat == types.StatusBroadcastJID || chat.Server == types.BroadcastServer {
+			continue
+		}
+		convs++
+		for _, hsm := range conv.GetMessages() {
+			evt, perr := cli.ParseWebMessage(chat, hsm.GetMessage())
+			if perr != nil {
+				skipped++
+				continue
+			}
+			if t := messageType(evt.Message); t == "reaction" || t == "protocol" || t == "unknown" {
+				skipped++
+				continue
+			}
+			e := normalizeMessage(evt)
+			if e.PushName == "" {
+				e.PushName = pushNames[e.Sender]
+			}
+			added, ierr := archiveInsert(tx, e, evt.Message, "history")
+			if ierr != nil {
+				return 0, 0, 0, ierr
+			}
+			if added {
+				imported++
+			} else {
+				skipped++
+			}
+		}
+		name := conv.GetName()
+		if name == "" {
+			name = conv.GetDisplayName()
+		}
+		if name == "" && chat.Server == types.DefaultUserServer {
+			name = pushNames[chat.String()]
+		}
+		if name != "" {
+			if _, err := tx.Exec(`UPDATE chats SET name = ? WHERE jid = ?`, name, chat.String()); err != nil {
+				return 0, 0, 0, err
+			}
+		}
+	}
+	return imported, skipped, convs, tx.Commit()
+}
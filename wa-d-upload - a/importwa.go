@@ -0,0 +1,282 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"wa-common/phone"
+	"wa-common/waexport"
+)
+
+/* ---------- Import WhatsApp chat export ---------- */
+
+// maxImportSize membatasi upload ZIP ekspor (chat + media). Isi ZIP dibatasi
+// terpisah karena ukuran terkompresi tidak menjamin ukuran hasil ekstrak:
+// lampiran yang lebih besar dari maxAttachmentSize, atau yang melewati total
+// maxExtractSize per import, dilewati (pesannya tetap diimport tanpa file).
+const (
+	maxImportSize     = 1 << 30
+	maxAttachmentSize = 256 << 20
+	maxExtractSize    = 4 << 30
+)
+
+// errAttachmentTooLarge dikembalikan extractAttachment kalau isi lampiran
+// melebihi batas.
+var errAttachmentTooLarge = errors.New("attachment too large")
+
+// importMediaDir menampung lampiran hasil import; bisa diganti lewat env IMPORT_MEDIA_DIR.
+func importMediaDir() string {
+	if dir := os.Getenv("IMPORT_MEDIA_DIR"); dir != "" {
+		return dir
+	}
+	return "imported-media"
+}
+
+/* ---------- Import API ---------- */
+
+func registerImportRoutes() {
+	http.HandleFunc("POST /chats/{jid}/import", importChatHandler)
+}
+
+type importResult struct {
+	Chat        string `json:"chat"`
+	DateOrder   string `json:"date_order"`
+	Parsed      int    `json:"parsed"`
+	Imported    int    `json:"imported"`
+	Duplicates  int    `json:"duplicates"`
+	System      int    `json:"system_lines"`
+	Attachments int    `json:"attachments"`
+	Missing     int    `json:"missing_attachments"`
+	Skipped     int    `json:"skipped_attachments"` // melebihi maxAttachmentSize/maxExtractSize
+}
+
+// importChatHandler menerima multipart form:
+//
+//	file        ZIP hasil "Ekspor chat" (atau _chat.txt saja)
+//	self        nama kita di ekspor, supaya pesan itu ditandai from_me
+//	date_order  dmy | mdy (opsional, default ditebak)
+//	tz          zona waktu HP saat ekspor, mis. Asia/Jakarta (default zona server)
+//
+// Import bisa diulang: pesan yang sama mendapat id yang sama dan dilewati.
+func importChatHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireArchive(w) {
+		return
+	}
+	jid, err := phone.NormalizeJID(r.PathValue("jid"))
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeError(w, 400, "expected multipart form with a file field: "+err.Error())
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, 400, "file is required")
+		return
+	}
+	defer file.Close()
+
+	loc := time.Local
+	if tz := r.FormValue("tz"); tz != "" {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			writeError(w, 400, "unknown tz: "+tz)
+			return
+		}
+	}
+
+	var chatTxt io.Reader = file
+	var zr *zip.Reader
+	if strings.HasSuffix(strings.ToLower(header.Filename), ".zip") {
+		if zr, err = zip.NewReader(file, header.Size); err != nil {
+			writeError(w, 400, "invalid zip: "+err.Error())
+			return
+		}
+		txt := waexport.FindChatTxt(zr)
+		if txt == nil {
+			writeError(w, 400, "zip has no chat .txt file")
+			return
+		}
+		rc, err := txt.Open()
+		if err != nil {
+			writeError(w, 400, err.Error())
+			return
+		}
+		defer rc.Close()
+		chatTxt = rc
+	}
+
+	lines, order, err := waexport.Parse(chatTxt, r.FormValue("date_order"), loc)
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	res, err := importWALines(jid, lines, strings.TrimSpace(r.FormValue("self")), zr)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	res.DateOrder = order
+	writeJSON(w, res)
+}
+
+func importWALines(chat types.JID, lines []waexport.Line, self string, zr *zip.Reader) (*importResult, error) {
+	res := &importResult{Chat: chat.String(), Parsed: len(lines)}
+	isGroup := chat.Server == types.GroupServer
+	selfJID := ""
+	if cli.Store.ID != nil {
+		selfJID = cli.Store.ID.ToNonAD().String()
+	}
+
+	media := map[string]*zip.File{}
+	if zr != nil {
+		for _, f := range zr.File {
+			media[path.Base(f.Name)] = f
+		}
+	}
+	dir := filepath.Join(importMediaDir(), chat.User)
+
+	tx, err := gwDB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	seen := map[string]int{}
+	var last time.Time
+	var extracted uint64
+	for _, l := range lines {
+		if l.System {
+			res.System++
+			continue
+		}
+		// id deterministik: waktu + nama + isi, plus urutan kalau ada yang kembar
+		key := l.Time.Format(time.RFC3339) + "\x00" + l.Name + "\x00" + l.Text + "\x00" + l.File
+		seen[key]++
+		sum := sha256.Sum256([]byte(key + "\x00" + strconv.Itoa(seen[key])))
+		id := "imp-" + hex.EncodeToString(sum[:10])
+
+		fromMe := self != "" && l.Name == self
+		sender, name := "", l.Name
+		switch {
+		case fromMe:
+			sender = selfJID
+		case !isGroup:
+			sender = chat.String()
+		default:
+			if p, err := phone.Normalize(l.Name); err == nil {
+				sender = types.NewJID(p, types.DefaultUserServer).String()
+			}
+		}
+
+		// cek duplikat dulu supaya import ulang tidak mengekstrak lampiran lagi
+		var exists bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM messages WHERE chat = ? AND id = ?)`, chat.String(), id).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if exists {
+			res.Duplicates++
+			continue
+		}
+
+		typ, mimetype, file := "text", "", ""
+		if l.Media {
+			typ = "media"
+			if l.File != "" {
+				res.Attachments++
+				typ, mimetype = waexport.AttachmentType(l.File)
+				zf := media[l.File]
+				switch {
+				case zf == nil:
+					res.Missing++
+				case zf.UncompressedSize64 > maxAttachmentSize || extracted+zf.UncompressedSize64 > maxExtractSize:
+					res.Skipped++
+				default:
+					file, err = extractAttachment(zf, dir)
+					switch {
+					case errors.Is(err, errAttachmentTooLarge):
+						res.Skipped++
+					case err != nil:
+						return nil, err
+					default:
+						// hanya yang benar-benar diekstrak yang memakai kuota
+						extracted += zf.UncompressedSize64
+					}
+				}
+			}
+		}
+
+		if _, err := tx.Exec(`INSERT INTO messages
+			(chat, id, sender, push_name, from_me, time, type, text, mimetype, file, edited, revoked, source)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'import')`,
+			chat.String(), id, sender, name, fromMe, l.Time.Unix(), typ, l.Text, mimetype, file, l.Edited, l.Revoked); err != nil {
+			return nil, err
+		}
+		res.Imported++
+		last = l.Time
+	}
+	if res.Imported > 0 {
+		chatName := ""
+		if !isGroup {
+			for _, l := range lines {
+				if !l.System && l.Name != self {
+					chatName = l.Name
+					break
+				}
+			}
+		}
+		if err := touchChat(tx, chat.String(), chatName, isGroup, last); err != nil {
+			return nil, err
+		}
+	}
+	return res, tx.Commit()
+}
+
+// extractAttachment menyalin lampiran dari ZIP ke importMediaDir. Nama file
+// diambil dari base name saja supaya isi ZIP tidak bisa menulis ke luar folder.
+// Ukuran di header ZIP bisa bohong, jadi salinannya juga dibatasi
+// maxAttachmentSize; file yang kepotong dihapus.
+func extractAttachment(zf *zip.File, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	dst := filepath.Join(dir, filepath.Base(zf.Name))
+	if _, err := os.Stat(dst); err == nil {
+		return dst, nil
+	}
+	src, err := zf.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(out, io.LimitReader(src, maxAttachmentSize+1))
+	if err == nil && n > maxAttachmentSize {
+		err = errAttachmentTooLarge
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	return dst, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"google.golang.org/protobuf/proto"
+	"wa-common/phone"
+)
+
+/* ---------- Location ---------- */
+
+func registerLocationRoutes() {
+	http.HandleFunc("POST /send/location", sendLocationHandler)
+}
+
+type locationInfo struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name,omitempty"`
+	Address   string  `json:"address,omitempty"`
+	URL       string  `json:"url,omitempty"`
+	Live      bool    `json:"live"`
+	Accuracy  uint32  `json:"accuracy_meters,omitempty"`
+	Speed     float32 `json:"speed_mps,omitempty"`
+	Caption   string  `json:"caption,omitempty"`
+	Sequence  int64   `json:"sequence,omitempty"`
+}
+
+// sendLocationHandler menerima {"to":"...","latitude":-6.2,"longitude":106.8,"name":"...","address":"..."}.
+// Dengan "live":true dikirim sebagai LiveLocationMessage (posisi sekali,
+// gateway tidak mengirim update lanjutan); name/address/url tidak berlaku
+// untuk lokasi live dan ditolak.
+func sendLocationHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireLogin(w) {
+		return
+	}
+	var body struct {
+		To string `json:"to"`
+		// pointer supaya koordinat yang tidak diisi tidak jadi 0,0
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+		locationInfo
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, 400, "bad json")
+		return
+	}
+	if body.Latitude == nil || body.Longitude == nil {
+		writeError(w, 400, "latitude and longitude are required")
+		return
+	}
+	body.locationInfo.Latitude, body.locationInfo.Longitude = *body.Latitude, *body.Longitude
+	if lat, lng := body.locationInfo.Latitude, body.locationInfo.Longitude; lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		writeError(w, 400, "latitude/longitude out of range")
+		return
+	}
+	jid, err := phone.NormalizeJID(body.To)
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	if body.Live && (body.Name != "" || body.Address != "" || body.URL != "") {
+		writeError(w, 400, "name, address and url are not supported for live locations")
+		return
+	}
+	msg := buildLocation(&body.locationInfo)
+	resp, err := sendMessage(r.Context(), jid, msg)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"status": "sent", "id": resp.ID})
+}
+
+func buildLocation(l *locationInfo) *waProto.Message {
+	var accuracy *uint32
+	if l.Accuracy > 0 {
+		accuracy = proto.Uint32(l.Accuracy)
+	}
+	var speed *float32
+	if l.Speed > 0 {
+		speed = proto.Float32(l.Speed)
+	}
+	if l.Live {
+		return &waProto.Message{LiveLocationMessage: &waProto.LiveLocationMessage{
+			DegreesLatitude:  proto.Float64(l.Latitude),
+			DegreesLongitude: proto.Float64(l.Longitude),
+			AccuracyInMeters: accuracy,
+			SpeedInMps:       speed,
+			Caption:          optString(l.Caption),
+			SequenceNumber:   proto.Int64(l.Sequence),
+		}}
+	}
+	return &waProto.Message{LocationMessage: &waProto.LocationMessage{
+		DegreesLatitude:  proto.Float64(l.Latitude),
+		DegreesLongitude: proto.Float64(l.Longitude),
+		Name:             optString(l.Name),
+		Address:          optString(l.Address),
+		URL:              optString(l.URL),
+		AccuracyInMeters: accuracy,
+		SpeedInMps:       speed,
+		Comment:          optString(l.Caption),
+	}}
+}
+
+func parseLocation(l *waProto.LocationMessage) *locationInfo {
+	return &locationInfo{
+		Latitude:  l.GetDegreesLatitude(),
+		Longitude: l.GetDegreesLongitude(),
+		Name:      l.GetName(),
+		Address:   l.GetAddress(),
+		URL:       l.GetURL(),
+		Live:      l.GetIsLive(),
+		Accuracy:  l.GetAccuracyInMeters(),
+		Speed:     l.GetSpeedInMps(),
+		Caption:   l.GetComment(),
+	}
+}
+
+func parseLiveLocation(l *waProto.LiveLocationMessage) *locationInfo {
+	return &locationInfo{
+		Latitude:  l.GetDegreesLatitude(),
+		Longitude: l.GetDegreesLongitude(),
+		Live:      true,
+		Accuracy:  l.GetAccuracyInMeters(),
+		Speed:     l.GetSpeedInMps(),
+		Caption:   l.GetCaption(),
+		Sequence:  l.GetSequenceNumber(),
+	}
+}
+
+func optString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -5,11 +5,15 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"image/png"
+	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -22,6 +26,7 @@ import (
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
 	waLog "go.mau.fi/whatsmeow/util/log"
+	"wa-common/phone"
 )
 
 var (
@@ -35,6 +40,24 @@ func main() {
 	dbLog := waLog.Noop
 	ctx := context.Background()
 	container, _ := sqlstore.New(ctx, "sqlite3", "file:session.db?_foreign_keys=on", dbLog)
+	if err := openGatewayDB(); err != nil {
+		panic("gateway db: " + err.Error())
+	}
+	if err := openArchive(); err != nil {
+		panic("message archive: " + err.Error())
+	}
+	if err := loadCallPolicy(); err != nil {
+		panic("call policy: " + err.Error())
+	}
+	if err := loadRules(); err != nil {
+		panic("rules: " + err.Error())
+	}
+	if err := loadCommandConfig(); err != nil {
+		panic("commands: " + err.Error())
+	}
+	if err := loadFlows(); err != nil {
+		panic("flows: " + err.Error())
+	}
 	deviceStore, _ := container.GetFirstDevice(ctx)
 	cli = whatsmeow.NewClient(deviceStore, dbLog)
 	cli.AddEventHandler(eventHandler)
@@ -46,6 +69,25 @@ func main() {
 	http.HandleFunc("/webhook", webhookHandler) // GET / POST / DELETE
 	http.HandleFunc("/qr", qrHandler)
 	http.HandleFunc("/logout", logoutHandler)
+	http.HandleFunc("/wss", wssHandler)
+	registerGroupRoutes()
+	registerContactRoutes()
+	registerPresenceRoutes()
+	registerReadRoutes()
+	registerPollRoutes()
+	registerLocationRoutes()
+	registerContactCardRoutes()
+	registerStatusRoutes()
+	registerChatRoutes()
+	registerCallRoutes()
+	registerArchiveRoutes()
+	registerHistoryRoutes()
+	registerExportRoutes()
+	registerImportRoutes()
+	registerRuleRoutes()
+	registerCommandRoutes()
+	registerWebhookQueueRoutes()
+	registerFlowRoutes()
 
 	go http.ListenAndServe(":8080", nil)
 
@@ -78,11 +120,61 @@ func connectWhatsApp() {
 func eventHandler(raw interface{}) {
 	switch v := raw.(type) {
 	case *events.Message:
+		if handlePollMessage(v) {
+			return
+		}
 		if v.Info.IsFromMe {
+			// pesan dari HP sendiri tidak diteruskan ke webhook, tapi tetap diarsip
+			if t := messageType(v.Message); v.Info.Chat != types.StatusBroadcastJID && t != "reaction" && t != "protocol" {
+				archiveMessage(normalizeMessage(v), v.Message, "live")
+			}
+			return
+		}
+		if v.Info.Chat == types.StatusBroadcastJID {
+			handleStatusMessage(v)
+			return
+		}
+		if handleMessageUpdate(v) {
 			return
 		}
+		trackUnread(v.Info)
+		normalized := normalizeMessage(v)
+		archiveMessage(normalized, v.Message, "live")
 		decoded := decodeBase64Fields(v)
-		go pushWebhook(decoded)
+		if m, ok := decoded.(map[string]interface{}); ok {
+			m["normalized"] = normalized
+		}
+		// webhook tetap menerima payload mentah; /wss cukup versi normalisasi
+		go broadcast(gwEvent{Type: "message", Time: v.Info.Timestamp, Data: normalized})
+		// urutan: perintah, lalu sesi flow, baru rules auto-reply
+		if !handleCommand(normalized, v.Message) && !handleFlow(normalized) {
+			go applyRules(normalized, v.Message)
+		}
+		var delivered func()
+		if autoRead {
+			delivered = func() {
+				_ = markRead(chatJID(v.Info.MessageSource), []unreadRef{{ID: v.Info.ID, Chat: v.Info.Chat, Sender: v.Info.Sender}})
+			}
+		}
+		go pushWebhook(decoded, delivered)
+	case *events.GroupInfo:
+		handleGroupInfo(v)
+	case *events.JoinedGroup:
+		handleJoinedGroup(v)
+	case *events.Picture:
+		handlePicture(v)
+	case *events.Presence:
+		handlePresence(v)
+	case *events.ChatPresence:
+		handleChatPresence(v)
+	case *events.Receipt:
+		handleOwnReceipt(v)
+	case *events.Archive, *events.Pin, *events.Mute, *events.MarkChatAsRead, *events.ClearChat, *events.DeleteChat:
+		handleAppState(v)
+	case *events.CallOffer, *events.CallOfferNotice, *events.CallAccept, *events.CallTerminate, *events.CallReject:
+		handleCall(v)
+	case *events.HistorySync:
+		go handleHistorySync(v)
 	}
 }
 
@@ -118,18 +210,45 @@ func decode(v interface{}) interface{} {
 }
 
 /* ---------- Webhook Push ---------- */
-func pushWebhook(payload interface{}) {
-	whMutex.Lock()
-	defer whMutex.Unlock()
-	if len(webhookURLs) == 0 {
+var webhookClient = &http.Client{Timeout: 15 * time.Second}
+
+// pushWebhook mengirim payload ke semua webhook secara paralel. delivered
+// (boleh nil) dijalankan kalau minimal satu webhook membalas 2xx. Selama
+// pengiriman dijeda (POST /webhook/pause) payload masuk antrean dulu dan
+// delivered baru jalan saat antrean dikirim.
+func pushWebhook(payload interface{}, delivered func()) {
+	body, _ := json.Marshal(payload)
+	if enqueueWebhook(body, delivered) {
 		return
 	}
-	body, _ := json.Marshal(payload)
-	for _, url := range webhookURLs {
+	if deliverWebhook(body) && delivered != nil {
+		delivered()
+	}
+}
+
+func deliverWebhook(body []byte) bool {
+	urls := listWebhooks()
+	if len(urls) == 0 {
+		return false
+	}
+	var wg sync.WaitGroup
+	var delivered atomic.Bool
+	for _, url := range urls {
+		wg.Add(1)
 		go func(u string) {
-			http.Post(u, "application/json", bytes.NewReader(body))
+			defer wg.Done()
+			resp, err := webhookClient.Post(u, "application/json", bytes.NewReader(body))
+			if err != nil {
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				delivered.Store(true)
+			}
 		}(url)
 	}
+	wg.Wait()
+	return delivered.Load()
 }
 
 /* ---------- Handlers ---------- */
@@ -143,6 +262,10 @@ type loginResp struct {
 
 func loginHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(sessionStatus())
+}
+
+func sessionStatus() loginResp {
 	resp := loginResp{GeneratedAt: time.Now(), Status: "waiting"}
 	if cli.Store.ID != nil {
 		resp.Status = "logged_in"
@@ -152,7 +275,7 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 		resp.Status = "waiting_qr"
 		resp.QRFile = "/qr"
 	}
-	_ = json.NewEncoder(w).Encode(resp)
+	return resp
 }
 
 type sendPayload struct {
@@ -171,12 +294,12 @@ func sendHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"bad json"}`, 400)
 		return
 	}
-	jid, err := types.ParseJID(p.To)
+	jid, err := phone.NormalizeJID(p.To)
 	if err != nil {
-		http.Error(w, `{"error":"invalid JID"}`, 400)
+		writeError(w, 400, err.Error())
 		return
 	}
-	_, err = cli.SendMessage(context.Background(), jid, &waProto.Message{Conversation: &p.Message})
+	_, err = sendMessage(context.Background(), jid, &waProto.Message{Conversation: &p.Message})
 	if err != nil {
 		http.Error(w, `{"error":"`+err.Error()+`"}`, 500)
 		return
@@ -189,9 +312,7 @@ func webhookHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	switch r.Method {
 	case http.MethodGet:
-		whMutex.Lock()
-		defer whMutex.Unlock()
-		_ = json.NewEncoder(w).Encode(map[string][]string{"webhooks": webhookURLs})
+		_ = json.NewEncoder(w).Encode(map[string][]string{"webhooks": listWebhooks()})
 	case http.MethodPost:
 		var body struct {
 			URL string `json:"url"`
@@ -200,19 +321,8 @@ func webhookHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, `{"error":"bad json"}`, 400)
 			return
 		}
-		whMutex.Lock()
-		found := false
-		for _, u := range webhookURLs {
-			if u == body.URL {
-				found = true
-				break
-			}
-		}
-		if !found {
-			webhookURLs = append(webhookURLs, body.URL)
-		}
-		whMutex.Unlock()
-		_ = json.NewEncoder(w).Encode(map[string]interface{}{"added": body.URL, "total": len(webhookURLs)})
+		total := addWebhook(body.URL)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"added": body.URL, "total": total})
 	case http.MethodDelete:
 		var body struct {
 			URL string `json:"url"`
@@ -221,21 +331,45 @@ func webhookHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, `{"error":"bad json"}`, 400)
 			return
 		}
-		whMutex.Lock()
-		newList := []string{}
-		for _, u := range webhookURLs {
-			if u != body.URL {
-				newList = append(newList, u)
-			}
-		}
-		webhookURLs = newList
-		whMutex.Unlock()
-		_ = json.NewEncoder(w).Encode(map[string]interface{}{"removed": body.URL, "total": len(webhookURLs)})
+		total := removeWebhook(body.URL)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"removed": body.URL, "total": total})
 	default:
 		http.Error(w, `{"error":"method not allowed"}`, 405)
 	}
 }
 
+func listWebhooks() []string {
+	whMutex.Lock()
+	defer whMutex.Unlock()
+	return append([]string{}, webhookURLs...)
+}
+
+// addWebhook menambah url kalau belum ada dan mengembalikan jumlah webhook.
+func addWebhook(url string) int {
+	whMutex.Lock()
+	defer whMutex.Unlock()
+	for _, u := range webhookURLs {
+		if u == url {
+			return len(webhookURLs)
+		}
+	}
+	webhookURLs = append(webhookURLs, url)
+	return len(webhookURLs)
+}
+
+func removeWebhook(url string) int {
+	whMutex.Lock()
+	defer whMutex.Unlock()
+	newList := []string{}
+	for _, u := range webhookURLs {
+		if u != url {
+			newList = append(newList, u)
+		}
+	}
+	webhookURLs = newList
+	return len(webhookURLs)
+}
+
 func listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "GET only", 405)
@@ -271,3 +405,79 @@ func logoutHandler(w http.ResponseWriter, r *http.Request) {
 	_ = os.Remove("qr.png")
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "logged out"})
 }
+
+/* ---------- Helpers ---------- */
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// envBool membaca env seperti "1", "true", "on" / "0", "false", "off".
+func envBool(name string, def bool) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(name))) {
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	}
+	return def
+}
+
+// envPhones membaca daftar nomor/JID dipisah koma dari env. Entri yang tidak
+// valid dicatat ke log dan dilewati supaya salah ketik tidak membuat gateway
+// gagal start.
+func envPhones(name string) []string {
+	var list []string
+	for _, raw := range strings.Split(os.Getenv(name), ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		if _, err := phone.NormalizeJID(raw); err != nil {
+			log.Printf("%s: entri %q dilewati: %v", name, raw, err)
+			continue
+		}
+		list = append(list, raw)
+	}
+	return list
+}
+
+// envSet true kalau salah satu env diisi.
+func envSet(names ...string) bool {
+	for _, name := range names {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func requireLogin(w http.ResponseWriter) bool {
+	if cli.Store.ID == nil {
+		writeError(w, 400, "not logged in")
+		return false
+	}
+	return true
+}
+
+// parseUserJIDs menerima JID lengkap atau nomor dalam format apa pun
+// (0898..., +62 898-..., 628...).
+func parseUserJIDs(list []string) ([]types.JID, error) {
+	out := make([]types.JID, 0, len(list))
+	for _, s := range list {
+		jid, err := phone.NormalizeJID(s)
+		if err != nil {
+			return nil, err
+		}
+		if jid.Server != types.DefaultUserServer && jid.Server != types.HiddenUserServer {
+			return nil, fmt.Errorf("not a user JID: %s", s)
+		}
+		out = append(out, jid)
+	}
+	return out, nil
+}
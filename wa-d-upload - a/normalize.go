@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+/* ---------- Normalized message ---------- */
+
+// msgEvent adalah bentuk pesan yang sudah dinormalisasi. Di webhook ia ikut
+// dikirim sebagai field "normalized" di samping payload mentah, dan di /wss
+// sebagai event bertipe "message".
+type msgEvent struct {
+	ID       string        `json:"id"`
+	Chat     string        `json:"chat"`
+	Sender   string        `json:"sender"`
+	Phone    string        `json:"phone,omitempty"` // nomor pengirim, juga untuk pengirim @lid kalau diketahui
+	PushName string        `json:"push_name,omitempty"`
+	IsGroup  bool          `json:"is_group"`
+	FromMe   bool          `json:"from_me"`
+	Time     time.Time     `json:"time"`
+	Type     string        `json:"type"`
+	Text     string        `json:"text,omitempty"`
+	QuotedID string        `json:"quoted_id,omitempty"`
+	Location *locationInfo `json:"location,omitempty"`
+	Contacts []contactCard `json:"contacts,omitempty"`
+	msgFlags
+
+	archiveChat string // chatJID, kunci chat di arsip; kosong berarti Chat
+}
+
+func normalizeMessage(v *events.Message) msgEvent {
+	m := v.Message
+	e := msgEvent{
+		ID:       v.Info.ID,
+		Chat:     v.Info.Chat.String(),
+		Sender:   v.Info.Sender.ToNonAD().String(),
+		Phone:    senderPhone(v.Info.Sender, v.Info.SenderAlt),
+		PushName: v.Info.PushName,
+		IsGroup:  v.Info.IsGroup,
+		FromMe:   v.Info.IsFromMe,
+		Time:     v.Info.Timestamp,
+		Type:     messageType(m),
+		Text:     messageText(m),
+		msgFlags: flagsOf(v),
+
+		archiveChat: chatJID(v.Info.MessageSource).String(),
+	}
+	if ctx := contextInfo(m); ctx != nil {
+		e.QuotedID = ctx.GetStanzaID()
+	}
+	switch {
+	case m.GetLocationMessage() != nil:
+		e.Location = parseLocation(m.GetLocationMessage())
+	case m.GetLiveLocationMessage() != nil:
+		e.Location = parseLiveLocation(m.GetLiveLocationMessage())
+	case m.GetContactMessage() != nil:
+		e.Contacts = []contactCard{parseContactMessage(m.GetContactMessage())}
+	case m.GetContactsArrayMessage() != nil:
+		for _, c := range m.GetContactsArrayMessage().GetContacts() {
+			e.Contacts = append(e.Contacts, parseContactMessage(c))
+		}
+	}
+	return e
+}
+
+// phoneJID mengembalikan JID nomor telepon (@s.whatsapp.net) untuk user.
+// WhatsApp makin sering mengalamatkan pengirim lewat LID (@lid) yang tidak
+// berisi nomor; LID diterjemahkan lewat alt (mis. Info.SenderAlt) atau
+// pemetaan LID di store whatsmeow. Kalau tidak ketemu, ok false dan JID
+// aslinya dikembalikan. Semua pengecekan nomor (owner, allowlist, rule,
+// session flow) harus lewat fungsi ini.
+func phoneJID(jid, alt types.JID) (types.JID, bool) {
+	jid = jid.ToNonAD()
+	switch {
+	case jid.Server == types.DefaultUserServer:
+		return jid, true
+	case jid.Server != types.HiddenUserServer:
+		return jid, false
+	case alt.Server == types.DefaultUserServer:
+		return alt.ToNonAD(), true
+	}
+	if cli != nil && cli.Store.LIDs != nil {
+		if pn, err := cli.Store.LIDs.GetPNForLID(context.Background(), jid); err == nil && !pn.IsEmpty() {
+			return pn.ToNonAD(), true
+		}
+	}
+	return jid, false
+}
+
+// chatJID adalah kunci chat yang dipakai gateway (unread, arsip): chat
+// pribadi yang dialamatkan lewat @lid diterjemahkan ke JID nomor telepon
+// supaya satu lawan bicara tidak terpecah jadi dua chat. Grup dan broadcast
+// dikembalikan apa adanya.
+func chatJID(src types.MessageSource) types.JID {
+	alt := src.SenderAlt
+	if src.IsFromMe {
+		alt = src.RecipientAlt
+	}
+	jid, _ := phoneJID(src.Chat, alt)
+	return jid
+}
+
+// senderPhone adalah phoneJID dalam bentuk nomor saja; kosong kalau LID-nya
+// belum bisa diterjemahkan.
+func senderPhone(jid, alt types.JID) string {
+	if pn, ok := phoneJID(jid, alt); ok {
+		return pn.User
+	}
+	return ""
+}
+
+// messageText mengambil teks yang terbaca dari pesan: isi teks biasa,
+// extended text (reply/link), caption media, atau pertanyaan poll.
+func messageText(m *waProto.Message) string {
+	switch {
+	case m == nil:
+		return ""
+	case m.GetConversation() != "":
+		return m.GetConversation()
+	case m.GetExtendedTextMessage() != nil:
+		return m.GetExtendedTextMessage().GetText()
+	case m.GetImageMessage() != nil:
+		return m.GetImageMessage().GetCaption()
+	case m.GetVideoMessage() != nil:
+		return m.GetVideoMessage().GetCaption()
+	case m.GetDocumentMessage() != nil:
+		return m.GetDocumentMessage().GetCaption()
+	case m.GetLocationMessage() != nil:
+		return m.GetLocationMessage().GetName()
+	case m.GetLiveLocationMessage() != nil:
+		return m.GetLiveLocationMessage().GetCaption()
+	case m.GetContactMessage() != nil:
+		return m.GetContactMessage().GetDisplayName()
+	case m.GetContactsArrayMessage() != nil:
+		return m.GetContactsArrayMessage().GetDisplayName()
+	case pollCreation(m) != nil:
+		return pollCreation(m).GetName()
+	}
+	return ""
+}
+
+func messageType(m *waProto.Message) string {
+	switch {
+	case m == nil:
+		return "unknown"
+	case m.Conversation != nil, m.ExtendedTextMessage != nil:
+		return "text"
+	case m.ImageMessage != nil:
+		return "image"
+	case m.VideoMessage != nil:
+		return "video"
+	case m.AudioMessage != nil:
+		return "audio"
+	case m.DocumentMessage != nil:
+		return "document"
+	case m.StickerMessage != nil:
+		return "sticker"
+	case m.LocationMessage != nil:
+		return "location"
+	case m.LiveLocationMessage != nil:
+		return "live_location"
+	case m.ContactMessage != nil:
+		return "contact"
+	case m.ContactsArrayMessage != nil:
+		return "contacts"
+	case m.ReactionMessage != nil:
+		return "reaction"
+	case pollCreation(m) != nil:
+		return "poll"
+	case m.PollUpdateMessage != nil:
+		return "poll_vote"
+	case m.ProtocolMessage != nil:
+		return "protocol"
+	}
+	return "unknown"
+}
+
+func contextInfo(m *waProto.Message) *waProto.ContextInfo {
+	switch {
+	case m.GetExtendedTextMessage() != nil:
+		return m.GetExtendedTextMessage().GetContextInfo()
+	case m.GetImageMessage() != nil:
+		return m.GetImageMessage().GetContextInfo()
+	case m.GetVideoMessage() != nil:
+		return m.GetVideoMessage().GetContextInfo()
+	case m.GetAudioMessage() != nil:
+		return m.GetAudioMessage().GetContextInfo()
+	case m.GetDocumentMessage() != nil:
+		return m.GetDocumentMessage().GetContextInfo()
+	case m.GetStickerMessage() != nil:
+		return m.GetStickerMessage().GetContextInfo()
+	case m.GetLocationMessage() != nil:
+		return m.GetLocationMessage().GetContextInfo()
+	case m.GetContactMessage() != nil:
+		return m.GetContactMessage().GetContextInfo()
+	}
+	return nil
+}
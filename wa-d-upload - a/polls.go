@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"wa-common/phone"
+)
+
+/* ---------- Polls ---------- */
+
+type pollOptionResult struct {
+	Name   string   `json:"name"`
+	Votes  int      `json:"votes"`
+	Voters []string `json:"voters"`
+}
+
+type pollResult struct {
+	ID              string             `json:"id"`
+	Chat            string             `json:"chat"`
+	Sender          string             `json:"sender"`
+	Question        string             `json:"question"`
+	SelectableCount int                `json:"selectable_count"`
+	CreatedAt       time.Time          `json:"created_at"`
+	Options         []pollOptionResult `json:"options"`
+	TotalVoters     int                `json:"total_voters"`
+}
+
+func registerPollRoutes() {
+	http.HandleFunc("POST /send/poll", sendPollHandler)
+	http.HandleFunc("GET /polls/{id}", pollResultHandler)
+}
+
+// sendPollHandler menerima {"to":"...","question":"...","options":["a","b"],"multi":false}.
+// "selectable_count" boleh diisi langsung; 0 berarti bebas pilih berapa saja.
+func sendPollHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireLogin(w) {
+		return
+	}
+	var body struct {
+		To              string   `json:"to"`
+		Question        string   `json:"question"`
+		Options         []string `json:"options"`
+		Multi           bool     `json:"multi"`
+		SelectableCount *int     `json:"selectable_count"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Question == "" {
+		writeError(w, 400, "bad json")
+		return
+	}
+	if len(body.Options) < 2 || len(body.Options) > 12 {
+		writeError(w, 400, "a poll needs 2-12 options")
+		return
+	}
+	seen := map[string]bool{}
+	for _, o := range body.Options {
+		if o == "" || seen[o] {
+			writeError(w, 400, "poll options must be unique and non-empty")
+			return
+		}
+		seen[o] = true
+	}
+	jid, err := phone.NormalizeJID(body.To)
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	selectable := 1
+	if body.Multi {
+		selectable = 0
+	}
+	if body.SelectableCount != nil {
+		selectable = *body.SelectableCount
+	}
+
+	msg := cli.BuildPollCreation(body.Question, body.Options, selectable)
+	resp, err := sendMessage(r.Context(), jid, msg)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	sender := cli.Store.ID.ToNonAD()
+	if err := savePoll(resp.ID, jid, sender, body.Question, body.Options, int(msg.GetPollCreationMessage().GetSelectableOptionsCount()), resp.Timestamp); err != nil {
+		writeError(w, 500, "poll sent but not stored: "+err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"status": "sent", "id": resp.ID})
+}
+
+func pollResultHandler(w http.ResponseWriter, r *http.Request) {
+	res, err := loadPollResult(r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, 404, "poll not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, res)
+}
+
+func savePoll(id types.MessageID, chat, sender types.JID, question string, options []string, selectable int, ts time.Time) error {
+	tx, err := gwDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`INSERT OR IGNORE INTO polls (id, chat, sender, question, selectable_count, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		id, chat.String(), sender.String(), question, selectable, ts.Unix())
+	if err != nil {
+		return err
+	}
+	for i, name := range options {
+		hash := sha256.Sum256([]byte(name))
+		_, err = tx.Exec(`INSERT OR IGNORE INTO poll_options (poll_id, idx, name, hash) VALUES (?, ?, ?, ?)`, id, i, name, hash[:])
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func loadPollResult(id string) (*pollResult, error) {
+	res := &pollResult{ID: id}
+	var created int64
+	err := gwDB.QueryRow(`SELECT chat, sender, question, selectable_count, created_at FROM polls WHERE id = ?`, id).
+		Scan(&res.Chat, &res.Sender, &res.Question, &res.SelectableCount, &created)
+	if err != nil {
+		return nil, err
+	}
+	res.CreatedAt = time.Unix(created, 0)
+
+	rows, err := gwDB.Query(`SELECT name FROM poll_options WHERE poll_id = ? ORDER BY idx`, id)
+	if err != nil {
+		return nil, err
+	}
+	index := map[string]int{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[name] = len(res.Options)
+		res.Options = append(res.Options, pollOptionResult{Name: name, Voters: []string{}})
+	}
+	rows.Close()
+
+	rows, err = gwDB.Query(`SELECT voter, options FROM poll_votes WHERE poll_id = ? ORDER BY updated_at`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var voter, raw string
+		if err := rows.Scan(&voter, &raw); err != nil {
+			return nil, err
+		}
+		var selected []string
+		_ = json.Unmarshal([]byte(raw), &selected)
+		if len(selected) > 0 {
+			res.TotalVoters++
+		}
+		for _, name := range selected {
+			if i, ok := index[name]; ok {
+				res.Options[i].Votes++
+				res.Options[i].Voters = append(res.Options[i].Voters, voter)
+			}
+		}
+	}
+	return res, rows.Err()
+}
+
+/* ---------- Poll events ---------- */
+
+// handlePollMessage menyimpan poll baru dan mendekripsi vote. Mengembalikan
+// true kalau pesan adalah vote, yang tidak perlu diteruskan mentah ke webhook.
+func handlePollMessage(v *events.Message) bool {
+	if poll := pollCreation(v.Message); poll != nil {
+		options := make([]string, 0, len(poll.GetOptions()))
+		for _, o := range poll.GetOptions() {
+			options = append(options, o.GetOptionName())
+		}
+		_ = savePoll(v.Info.ID, v.Info.Chat, v.Info.Sender.ToNonAD(), poll.GetName(), options, int(poll.GetSelectableOptionsCount()), v.Info.Timestamp)
+		return false
+	}
+	update := v.Message.GetPollUpdateMessage()
+	if update == nil {
+		return false
+	}
+
+	pollID := update.GetPollCreationMessageKey().GetID()
+	voter := v.Info.Sender.ToNonAD()
+	vote, err := cli.DecryptPollVote(context.Background(), v)
+	if err != nil {
+		emit("poll.vote_error", v.Info.Timestamp, map[string]string{
+			"poll_id": pollID, "chat": v.Info.Chat.String(), "voter": voter.String(), "error": err.Error(),
+		})
+		return true
+	}
+
+	names := pollOptionNames(pollID)
+	selected := make([]string, 0, len(vote.GetSelectedOptions()))
+	for _, hash := range vote.GetSelectedOptions() {
+		if name, ok := names[hex.EncodeToString(hash)]; ok {
+			selected = append(selected, name)
+		} else {
+			selected = append(selected, hex.EncodeToString(hash))
+		}
+	}
+	raw, _ := json.Marshal(selected)
+	_, _ = gwDB.Exec(`INSERT INTO poll_votes (poll_id, voter, options, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (poll_id, voter) DO UPDATE SET options = excluded.options, updated_at = excluded.updated_at`,
+		pollID, voter.String(), string(raw), v.Info.Timestamp.Unix())
+
+	data := map[string]interface{}{
+		"poll_id":  pollID,
+		"chat":     v.Info.Chat.String(),
+		"voter":    voter.String(),
+		"selected": selected,
+	}
+	if res, err := loadPollResult(pollID); err == nil {
+		data["question"] = res.Question
+		data["tally"] = res.Options
+	}
+	emit("poll.vote", v.Info.Timestamp, data)
+	return true
+}
+
+// pollCreation mengambil poll dari semua versi PollCreationMessage.
+func pollCreation(m *waProto.Message) *waProto.PollCreationMessage {
+	switch {
+	case m.GetPollCreationMessage() != nil:
+		return m.GetPollCreationMessage()
+	case m.GetPollCreationMessageV2() != nil:
+		return m.GetPollCreationMessageV2()
+	case m.GetPollCreationMessageV3() != nil:
+		return m.GetPollCreationMessageV3()
+	}
+	return nil
+}
+
+// pollOptionNames memetakan hash SHA-256 (hex) ke nama opsi.
+func pollOptionNames(pollID string) map[string]string {
+	out := map[string]string{}
+	rows, err := gwDB.Query(`SELECT name, hash FROM poll_options WHERE poll_id = ?`, pollID)
+	if err != nil {
+		return out
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var hash []byte
+		if rows.Scan(&name, &hash) == nil {
+			out[hex.EncodeToString(hash)] = name
+		}
+	}
+	return out
+}
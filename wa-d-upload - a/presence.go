@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"wa-common/phone"
+)
+
+/* ---------- Presence ---------- */
+
+func registerPresenceRoutes() {
+	http.HandleFunc("POST /presence", setPresenceHandler)
+	http.HandleFunc("POST /presence/chat", chatPresenceHandler)
+	http.HandleFunc("POST /presence/subscribe", subscribePresenceHandler)
+}
+
+// setPresenceHandler menerima {"state":"available"} atau {"state":"unavailable"}.
+func setPresenceHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireLogin(w) {
+		return
+	}
+	var body struct {
+		State string `json:"state"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, 400, "bad json")
+		return
+	}
+	state := types.Presence(body.State)
+	if state != types.PresenceAvailable && state != types.PresenceUnavailable {
+		writeError(w, 400, "state must be available or unavailable")
+		return
+	}
+	if err := cli.SendPresence(state); err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"status": "ok", "state": body.State})
+}
+
+// chatPresenceHandler menerima {"to":"0812...","state":"composing|recording|paused"}.
+func chatPresenceHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireLogin(w) {
+		return
+	}
+	var body struct {
+		To    string `json:"to"`
+		State string `json:"state"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, 400, "bad json")
+		return
+	}
+	jid, err := phone.NormalizeJID(body.To)
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	var state types.ChatPresence
+	media := types.ChatPresenceMediaText
+	switch body.State {
+	case "composing":
+		state = types.ChatPresenceComposing
+	case "recording":
+		state = types.ChatPresenceComposing
+		media = types.ChatPresenceMediaAudio
+	case "paused":
+		state = types.ChatPresencePaused
+	default:
+		writeError(w, 400, "state must be composing, recording or paused")
+		return
+	}
+	if err := cli.SendChatPresence(jid, state, media); err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"status": "ok", "to": jid.String(), "state": body.State})
+}
+
+// subscribePresenceHandler menerima {"jid":"0812..."}. Server WhatsApp hanya
+// mengirim presence kontak kalau kita sendiri sedang "available".
+func subscribePresenceHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireLogin(w) {
+		return
+	}
+	var body struct {
+		JID string `json:"jid"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, 400, "bad json")
+		return
+	}
+	jids, err := parseUserJIDs([]string{body.JID})
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	if err := cli.SubscribePresence(jids[0]); err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"status": "subscribed", "jid": jids[0].String()})
+}
+
+/* ---------- Presence events ---------- */
+
+func handlePresence(v *events.Presence) {
+	data := map[string]interface{}{
+		"jid":       v.From.String(),
+		"available": !v.Unavailable,
+	}
+	if !v.LastSeen.IsZero() {
+		data["last_seen"] = v.LastSeen
+	}
+	emit("presence.contact", time.Now(), data)
+}
+
+func handleChatPresence(v *events.ChatPresence) {
+	state := string(v.State)
+	if v.State == types.ChatPresenceComposing && v.Media == types.ChatPresenceMediaAudio {
+		state = "recording"
+	}
+	emit("presence.chat", time.Now(), map[string]interface{}{
+		"chat":     v.Chat.String(),
+		"sender":   v.Sender.ToNonAD().String(),
+		"is_group": v.IsGroup,
+		"state":    state,
+	})
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"wa-common/phone"
+)
+
+/* ---------- Read receipts ---------- */
+
+// Konfigurasi lewat env:
+//
+//	AUTO_READ=1        tandai pesan masuk sudah dibaca setelah webhook sukses (2xx)
+//	READ_RECEIPTS=0    jangan pernah kirim centang biru ke pengirim; chat tetap
+//	                   ditandai dibaca di HP kita sendiri (receipt "read-self")
+var (
+	autoRead     = envBool("AUTO_READ", false)
+	readReceipts = envBool("READ_RECEIPTS", true)
+)
+
+// unreadRef adalah pesan masuk yang belum ditandai dibaca sejak gateway jalan.
+// Chat dan Sender disimpan apa adanya (bisa @lid) karena receipt harus
+// dikirim ke alamat yang dipakai pesan aslinya.
+type unreadRef struct {
+	ID     types.MessageID
+	Chat   types.JID
+	Sender types.JID
+	Time   time.Time
+}
+
+var (
+	unreadMu sync.Mutex
+	unread   = map[types.JID][]unreadRef{} // kunci: chatJID
+)
+
+func registerReadRoutes() {
+	http.HandleFunc("POST /read", markReadHandler)
+}
+
+// maxUnreadPerChat membatasi memori untuk chat yang tidak pernah dibaca.
+const maxUnreadPerChat = 200
+
+func trackUnread(info types.MessageInfo) {
+	chat := chatJID(info.MessageSource)
+	unreadMu.Lock()
+	refs := append(unread[chat], unreadRef{ID: info.ID, Chat: info.Chat, Sender: info.Sender, Time: info.Timestamp})
+	if len(refs) > maxUnreadPerChat {
+		refs = refs[len(refs)-maxUnreadPerChat:]
+	}
+	unread[chat] = refs
+	unreadMu.Unlock()
+}
+
+// forgetUnread membuang pesan yang sudah dibaca, mis. lewat HP sendiri.
+func forgetUnread(chat types.JID, ids []types.MessageID) {
+	drop := make(map[types.MessageID]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	unreadMu.Lock()
+	defer unreadMu.Unlock()
+	kept := unread[chat][:0]
+	for _, ref := range unread[chat] {
+		if !drop[ref.ID] {
+			kept = append(kept, ref)
+		}
+	}
+	if len(kept) == 0 {
+		delete(unread, chat)
+	} else {
+		unread[chat] = kept
+	}
+}
+
+// markRead mengirim receipt per alamat chat dan pengirim, karena MarkRead
+// hanya menerima pesan dari satu pengirim sekaligus. chat adalah kunci
+// chatJID; ref tanpa Chat dikirim ke chat itu.
+func markRead(chat types.JID, refs []unreadRef) error {
+	receipt := types.ReceiptTypeRead
+	if !readReceipts {
+		receipt = types.ReceiptTypeReadSelf
+	}
+	type target struct{ chat, sender types.JID }
+	byTarget := map[target][]types.MessageID{}
+	for _, ref := range refs {
+		t := target{chat: ref.Chat, sender: ref.Sender.ToNonAD()}
+		if t.chat.IsEmpty() {
+			t.chat = chat
+		}
+		byTarget[t] = append(byTarget[t], ref.ID)
+	}
+	var ids []types.MessageID
+	for t, targetIDs := range byTarget {
+		if err := cli.MarkRead(targetIDs, time.Now(), t.chat, t.sender, receipt); err != nil {
+			return err
+		}
+		ids = append(ids, targetIDs...)
+	}
+	forgetUnread(chat, ids)
+	return nil
+}
+
+// markReadHandler menerima {"chat":"0812...","ids":["3EB0..."],"sender":"..."}.
+// Tanpa "ids", semua pesan masuk chat itu yang belum dibaca akan ditandai.
+func markReadHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireLogin(w) {
+		return
+	}
+	var body struct {
+		Chat   string   `json:"chat"`
+		Sender string   `json:"sender"`
+		IDs    []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Chat == "" {
+		writeError(w, 400, "bad json")
+		return
+	}
+	chat, err := phone.NormalizeJID(body.Chat)
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	chat, _ = phoneJID(chat, types.EmptyJID)
+
+	unreadMu.Lock()
+	pending := append([]unreadRef(nil), unread[chat]...)
+	unreadMu.Unlock()
+	var refs []unreadRef
+	if len(body.IDs) == 0 {
+		refs = pending
+	} else {
+		sender := chat
+		if body.Sender != "" {
+			if sender, err = phone.NormalizeJID(body.Sender); err != nil {
+				writeError(w, 400, err.Error())
+				return
+			}
+		} else if chat.Server == types.GroupServer {
+			writeError(w, 400, "sender is required for group messages")
+			return
+		}
+		known := make(map[types.MessageID]unreadRef, len(pending))
+		for _, ref := range pending {
+			known[ref.ID] = ref
+		}
+		for _, id := range body.IDs {
+			// pesan yang tercatat memakai alamat aslinya (mungkin @lid)
+			if ref, ok := known[id]; ok {
+				refs = append(refs, ref)
+			} else {
+				refs = append(refs, unreadRef{ID: id, Sender: sender})
+			}
+		}
+	}
+	if len(refs) == 0 {
+		writeJSON(w, map[string]interface{}{"status": "nothing to mark", "chat": chat.String(), "marked": 0})
+		return
+	}
+	if err := markRead(chat, refs); err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]interface{}{"status": "read", "chat": chat.String(), "marked": len(refs)})
+}
+
+// handleOwnReceipt membersihkan daftar unread ketika chat dibaca dari device
+// lain milik kita.
+func handleOwnReceipt(v *events.Receipt) {
+	if v.IsFromMe && (v.Type == types.ReceiptTypeRead || v.Type == types.ReceiptTypeReadSelf) {
+		forgetUnread(chatJID(v.MessageSource), v.MessageIDs)
+	}
+}
@@ -0,0 +1,768 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/appstate"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+	"wa-common/phone"
+)
+
+/* ---------- Auto-reply rules ---------- */
+
+// Rules dicek berurutan untuk setiap pesan masuk. Semua syarat di match harus
+// terpenuhi (syarat kosong berarti apa saja); aksi dari setiap rule yang cocok
+// dijalankan, sampai ada aksi "stop". Contoh YAML (RULES_FILE=rules.yaml):
+//
+//	rules:
+//	  - name: jam-kerja
+//	    match:
+//	      keywords: [harga, price list]
+//	      chat_type: private
+//	      time: {from: "17:00", to: "08:00", tz: Asia/Jakarta}
+//	    actions:
+//	      - type: reply
+//	        text: "Halo {{.Name}}, kami buka lagi jam 08.00 ya."
+//	      - type: stop
+//
+// Tanpa RULES_FILE, rules dikelola lewat PUT /rules dan disimpan di gateway.db.
+type rule struct {
+	Name     string       `json:"name" yaml:"name"`
+	Disabled bool         `json:"disabled,omitempty" yaml:"disabled"`
+	Match    ruleMatch    `json:"match" yaml:"match"`
+	Actions  []ruleAction `json:"actions" yaml:"actions"`
+
+	tmpl []*template.Template // sejajar dengan Actions; nil kalau tanpa teks
+}
+
+type ruleMatch struct {
+	Keywords []string    `json:"keywords,omitempty" yaml:"keywords"` // salah satu, tidak peka huruf besar
+	Regex    string      `json:"regex,omitempty" yaml:"regex"`
+	ChatType string      `json:"chat_type,omitempty" yaml:"chat_type"` // private | group
+	Chats    []string    `json:"chats,omitempty" yaml:"chats"`
+	Senders  []string    `json:"senders,omitempty" yaml:"senders"`
+	Types    []string    `json:"types,omitempty" yaml:"types"` // text, image, ...
+	Time     *timeWindow `json:"time,omitempty" yaml:"time"`
+
+	re *regexp.Regexp
+}
+
+// timeWindow berlaku dari From sampai To (jam:menit). Kalau From > To,
+// jendela melewati tengah malam.
+type timeWindow struct {
+	From string   `json:"from" yaml:"from"`
+	To   string   `json:"to" yaml:"to"`
+	Days []string `json:"days,omitempty" yaml:"days"` // mon, tue, ...
+	TZ   string   `json:"tz,omitempty" yaml:"tz"`
+
+	from, to int // menit sejak 00:00
+	loc      *time.Location
+}
+
+// ruleAction: reply, send_media, forward, label, webhook, stop.
+type ruleAction struct {
+	Type     string `json:"type" yaml:"type"`
+	Text     string `json:"text,omitempty" yaml:"text"`           // reply; caption untuk send_media
+	Quote    bool   `json:"quote,omitempty" yaml:"quote"`         // reply sambil mengutip pesan
+	URL      string `json:"url,omitempty" yaml:"url"`             // send_media, webhook
+	Media    string `json:"media,omitempty" yaml:"media"`         // image | video | audio | document
+	FileName string `json:"file_name,omitempty" yaml:"file_name"` // nama dokumen
+	To       string `json:"to,omitempty" yaml:"to"`               // forward
+	Label    string `json:"label,omitempty" yaml:"label"`         // ID label WhatsApp Business
+
+	to types.JID
+}
+
+type ruleSet struct {
+	Rules []*rule `json:"rules" yaml:"rules"`
+}
+
+const rulesKey = "rules"
+
+var (
+	rulesMu sync.RWMutex
+	rules   ruleSet
+)
+
+func registerRuleRoutes() {
+	http.HandleFunc("GET /rules", getRulesHandler)
+	http.HandleFunc("PUT /rules", putRulesHandler)
+	http.HandleFunc("POST /rules/reload", reloadRulesHandler)
+	http.HandleFunc("POST /rules/test", testRulesHandler)
+}
+
+// loadRules dipanggil sekali setelah gateway.db dibuka. Kalau RULES_FILE
+// diisi, file itu sumber utama dan PUT /rules ditolak.
+func loadRules() error {
+	var set ruleSet
+	if file := os.Getenv("RULES_FILE"); file != "" {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(raw, &set); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	} else if _, err := loadSetting(rulesKey, &set); err != nil {
+		return err
+	}
+	if err := compileRules(&set); err != nil {
+		return err
+	}
+	rulesMu.Lock()
+	rules = set
+	rulesMu.Unlock()
+	return nil
+}
+
+var ruleActionTypes = map[string]bool{
+	"reply": true, "send_media": true, "forward": true, "label": true, "webhook": true, "stop": true,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+	"min": time.Sunday, "sen": time.Monday, "sel": time.Tuesday, "rab": time.Wednesday,
+	"kam": time.Thursday, "jum": time.Friday, "sab": time.Saturday,
+}
+
+// compileRules memvalidasi rules dan menyiapkan regex, template dan JID
+// supaya pengecekan per pesan tidak perlu parsing ulang.
+func compileRules(set *ruleSet) error {
+	for i, r := range set.Rules {
+		if r == nil {
+			return fmt.Errorf("rule #%d is empty", i+1)
+		}
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		fail := func(format string, args ...interface{}) error {
+			return fmt.Errorf("rule %q: %s", r.Name, fmt.Sprintf(format, args...))
+		}
+		if err := r.Match.compile(); err != nil {
+			return fail("%v", err)
+		}
+		if len(r.Actions) == 0 {
+			return fail("no actions")
+		}
+		r.tmpl = make([]*template.Template, len(r.Actions))
+		for j := range r.Actions {
+			a := &r.Actions[j]
+			if !ruleActionTypes[a.Type] {
+				return fail("unknown action type %q", a.Type)
+			}
+			switch {
+			case a.Type == "reply" && a.Text == "":
+				return fail("reply needs text")
+			case a.Type == "send_media" && (a.URL == "" || !mediaKinds[a.Media]):
+				return fail("send_media needs url and media (image, video, audio or document)")
+			case a.Type == "webhook" && a.URL == "":
+				return fail("webhook needs url")
+			case a.Type == "label" && a.Label == "":
+				return fail("label needs label id")
+			case a.Type == "forward":
+				jid, err := phone.NormalizeJID(a.To)
+				if err != nil {
+					return fail("forward: %v", err)
+				}
+				a.to = jid
+			}
+			if a.Text != "" {
+				t, err := template.New(r.Name).Option("missingkey=zero").Parse(a.Text)
+				if err != nil {
+					return fail("template: %v", err)
+				}
+				r.tmpl[j] = t
+			}
+		}
+	}
+	return nil
+}
+
+// compile memvalidasi syarat match dan menyiapkan regex dan JID-nya. Dipakai
+// juga oleh trigger flow.
+func (m *ruleMatch) compile() error {
+	if m.Regex != "" {
+		re, err := regexp.Compile(m.Regex)
+		if err != nil {
+			return fmt.Errorf("bad regex: %v", err)
+		}
+		m.re = re
+	}
+	switch m.ChatType {
+	case "", "any", "private", "group":
+	default:
+		return errors.New("chat_type must be private or group")
+	}
+	for j, s := range m.Chats {
+		jid, err := phone.NormalizeJID(s)
+		if err != nil {
+			return err
+		}
+		m.Chats[j] = jid.String()
+	}
+	for j, s := range m.Senders {
+		jid, err := phone.NormalizeJID(s)
+		if err != nil {
+			return err
+		}
+		m.Senders[j] = jid.User
+	}
+	if tw := m.Time; tw != nil {
+		var err error
+		if tw.from, err = clockMinutes(tw.From); err != nil {
+			return fmt.Errorf("time.from: %v", err)
+		}
+		if tw.to, err = clockMinutes(tw.To); err != nil {
+			return fmt.Errorf("time.to: %v", err)
+		}
+		tw.loc = time.Local
+		if tw.TZ != "" {
+			if tw.loc, err = time.LoadLocation(tw.TZ); err != nil {
+				return fmt.Errorf("unknown tz %q", tw.TZ)
+			}
+		}
+		for _, d := range tw.Days {
+			if _, ok := weekdays[strings.ToLower(d)]; !ok {
+				return fmt.Errorf("unknown day %q", d)
+			}
+		}
+	}
+	return nil
+}
+
+var mediaKinds = map[string]bool{"image": true, "video": true, "audio": true, "document": true}
+
+// clockMinutes menerima "08:00" atau "08.00"; "24:00" boleh sebagai akhir hari.
+func clockMinutes(s string) (int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(strings.Replace(s, ".", ":", 1), "%d:%d", &h, &m); err != nil ||
+		h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("expected HH:MM, got %q", s)
+	}
+	return h*60 + m, nil
+}
+
+/* ---------- Matching ---------- */
+
+// ruleData adalah data template di teks aksi, mis. {{.Name}} atau {{index .Match 1}}.
+type ruleData struct {
+	Text   string
+	Name   string
+	Sender string
+	Number string
+	Chat   string
+	Type   string
+	Time   time.Time
+	Match  []string          // hasil regex: seluruh teks lalu capture group
+	Groups map[string]string // capture group bernama
+}
+
+// plannedAction adalah aksi yang sudah dirender tapi belum dijalankan,
+// dipakai bersama oleh eksekusi asli dan dry-run.
+type plannedAction struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Quote    bool   `json:"quote,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Media    string `json:"media,omitempty"`
+	FileName string `json:"file_name,omitempty"`
+	To       string `json:"to,omitempty"`
+	Label    string `json:"label,omitempty"`
+	Error    string `json:"error,omitempty"`
+
+	to types.JID
+}
+
+type ruleHit struct {
+	Rule    string          `json:"rule"`
+	Actions []plannedAction `json:"actions"`
+}
+
+func (r *rule) matches(e msgEvent) (ruleData, bool) {
+	data := newRuleData(e)
+	if r.Disabled {
+		return data, false
+	}
+	return data, r.Match.matches(e, &data)
+}
+
+func newRuleData(e msgEvent) ruleData {
+	data := ruleData{Text: e.Text, Name: e.PushName, Sender: e.Sender, Chat: e.Chat, Type: e.Type, Time: e.Time}
+	// Number selalu nomor telepon (dari e.Phone) supaya "senders" dan
+	// {{.Number}} tetap jalan untuk pengirim @lid; LID yang belum diketahui
+	// nomornya jatuh ke user LID.
+	data.Number = e.Phone
+	if data.Number == "" {
+		if jid, err := types.ParseJID(e.Sender); err == nil {
+			data.Number = jid.User
+		}
+	}
+	if data.Name == "" {
+		data.Name = data.Number
+	}
+	return data
+}
+
+// matches mengecek semua syarat dan mengisi hasil regex ke data.
+func (m *ruleMatch) matches(e msgEvent, data *ruleData) bool {
+	switch m.ChatType {
+	case "private":
+		if e.IsGroup {
+			return false
+		}
+	case "group":
+		if !e.IsGroup {
+			return false
+		}
+	}
+	if len(m.Chats) > 0 && !containsString(m.Chats, e.Chat) {
+		return false
+	}
+	if len(m.Senders) > 0 && !containsString(m.Senders, data.Number) {
+		return false
+	}
+	if len(m.Types) > 0 && !containsString(m.Types, e.Type) {
+		return false
+	}
+	if m.Time != nil && !m.Time.contains(e.Time) {
+		return false
+	}
+	if len(m.Keywords) > 0 {
+		text, found := strings.ToLower(e.Text), false
+		for _, k := range m.Keywords {
+			if k != "" && strings.Contains(text, strings.ToLower(k)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if m.re != nil {
+		sub := m.re.FindStringSubmatch(e.Text)
+		if sub == nil {
+			return false
+		}
+		data.Match = sub
+		data.Groups = map[string]string{}
+		for i, name := range m.re.SubexpNames() {
+			if name != "" {
+				data.Groups[name] = sub[i]
+			}
+		}
+	}
+	return true
+}
+
+func (tw *timeWindow) contains(t time.Time) bool {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	t = t.In(tw.loc)
+	if len(tw.Days) > 0 {
+		ok := false
+		for _, d := range tw.Days {
+			if weekdays[strings.ToLower(d)] == t.Weekday() {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	now := t.Hour()*60 + t.Minute()
+	if tw.from <= tw.to {
+		return now >= tw.from && now < tw.to
+	}
+	return now >= tw.from || now < tw.to
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// planRules mencari rule yang cocok dan merender aksinya tanpa menjalankan apa pun.
+func planRules(set ruleSet, e msgEvent) []ruleHit {
+	var hits []ruleHit
+	for _, r := range set.Rules {
+		data, ok := r.matches(e)
+		if !ok {
+			continue
+		}
+		hit := ruleHit{Rule: r.Name}
+		stop := false
+		for i, a := range r.Actions {
+			p := plannedAction{Type: a.Type, Quote: a.Quote, URL: a.URL, Media: a.Media,
+				FileName: a.FileName, Label: a.Label, to: a.to}
+			if !a.to.IsEmpty() {
+				p.To = a.to.String()
+			}
+			if t := r.tmpl[i]; t != nil {
+				var buf bytes.Buffer
+				if err := t.Execute(&buf, data); err != nil {
+					p.Error = err.Error()
+				}
+				p.Text = buf.String()
+			}
+			hit.Actions = append(hit.Actions, p)
+			if a.Type == "stop" {
+				stop = true
+				break
+			}
+		}
+		hits = append(hits, hit)
+		if stop {
+			break
+		}
+	}
+	return hits
+}
+
+/* ---------- Execution ---------- */
+
+// applyRules dijalankan untuk setiap pesan masuk (bukan dari kita sendiri).
+// Hasil setiap rule yang cocok dikirim sebagai event rule.matched.
+func applyRules(e msgEvent, m *waProto.Message) {
+	rulesMu.RLock()
+	set := rules
+	rulesMu.RUnlock()
+	if len(set.Rules) == 0 || e.FromMe {
+		return
+	}
+	for _, hit := range planRules(set, e) {
+		for i := range hit.Actions {
+			a := &hit.Actions[i]
+			if a.Error != "" {
+				continue
+			}
+			if err := runRuleAction(*a, e, m, hit.Rule); err != nil {
+				a.Error = err.Error()
+			}
+		}
+		emit("rule.matched", time.Now(), map[string]interface{}{
+			"rule":       hit.Rule,
+			"message_id": e.ID,
+			"chat":       e.Chat,
+			"sender":     e.Sender,
+			"actions":    hit.Actions,
+		})
+	}
+}
+
+func runRuleAction(a plannedAction, e msgEvent, m *waProto.Message, ruleName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	chat, err := types.ParseJID(e.Chat)
+	if err != nil {
+		return err
+	}
+	switch a.Type {
+	case "reply":
+		msg := &waProto.Message{Conversation: proto.String(a.Text)}
+		if a.Quote {
+			msg = &waProto.Message{ExtendedTextMessage: &waProto.ExtendedTextMessage{
+				Text:        proto.String(a.Text),
+				ContextInfo: quoteContext(e, m),
+			}}
+		}
+		_, err = sendMessage(ctx, chat, msg)
+	case "send_media":
+		var msg *waProto.Message
+		if msg, err = fetchMedia(ctx, a.URL, a.Media, a.Text, a.FileName); err == nil {
+			_, err = sendMessage(ctx, chat, msg)
+		}
+	case "forward":
+		_, err = sendMessage(ctx, a.to, forwardCopy(m))
+	case "label":
+		err = cli.SendAppState(ctx, appstate.BuildLabelChat(chat, a.Label, true))
+	case "webhook":
+		body, _ := json.Marshal(gwEvent{Type: "rule.webhook", Time: time.Now(), Data: map[string]interface{}{
+			"rule": ruleName, "message": e,
+		}})
+		var req *http.Request
+		if req, err = http.NewRequestWithContext(ctx, http.MethodPost, a.URL, bytes.NewReader(body)); err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		var resp *http.Response
+		if resp, err = webhookClient.Do(req); err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			err = fmt.Errorf("webhook returned %s", resp.Status)
+		}
+	}
+	return err
+}
+
+func quoteContext(e msgEvent, m *waProto.Message) *waProto.ContextInfo {
+	ctx := &waProto.ContextInfo{StanzaID: proto.String(e.ID), QuotedMessage: m}
+	if e.IsGroup {
+		ctx.Participant = proto.String(e.Sender)
+	}
+	return ctx
+}
+
+// forwardCopy menyalin pesan dan menandainya "Diteruskan". Media tidak perlu
+// diupload ulang karena media key ikut tersalin.
+func forwardCopy(m *waProto.Message) *waProto.Message {
+	msg := proto.Clone(m).(*waProto.Message)
+	fwd := &waProto.ContextInfo{IsForwarded: proto.Bool(true), ForwardingScore: proto.Uint32(1)}
+	switch {
+	case msg.Conversation != nil:
+		msg = &waProto.Message{ExtendedTextMessage: &waProto.ExtendedTextMessage{
+			Text: msg.Conversation, ContextInfo: fwd,
+		}}
+	case msg.ExtendedTextMessage != nil:
+		msg.ExtendedTextMessage.ContextInfo = fwd
+	case msg.ImageMessage != nil:
+		msg.ImageMessage.ContextInfo = fwd
+	case msg.VideoMessage != nil:
+		msg.VideoMessage.ContextInfo = fwd
+	case msg.AudioMessage != nil:
+		msg.AudioMessage.ContextInfo = fwd
+	case msg.DocumentMessage != nil:
+		msg.DocumentMessage.ContextInfo = fwd
+	case msg.StickerMessage != nil:
+		msg.StickerMessage.ContextInfo = fwd
+	case msg.LocationMessage != nil:
+		msg.LocationMessage.ContextInfo = fwd
+	case msg.ContactMessage != nil:
+		msg.ContactMessage.ContextInfo = fwd
+	}
+	return msg
+}
+
+// maxRuleMedia membatasi file yang diambil aksi send_media.
+const maxRuleMedia = 64 << 20
+
+// fetchMedia mengunduh file dari url lalu mengunggahnya ke server media WhatsApp.
+func fetchMedia(ctx context.Context, url, kind, caption, fileName string) (*waProto.Message, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("media url returned %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRuleMedia+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxRuleMedia {
+		return nil, errors.New("media too large")
+	}
+	mimetype := resp.Header.Get("Content-Type")
+	if mimetype == "" || mimetype == "application/octet-stream" {
+		if t := mime.TypeByExtension(path.Ext(req.URL.Path)); t != "" {
+			mimetype = t
+		} else {
+			mimetype = http.DetectContentType(data)
+		}
+	}
+	if fileName == "" {
+		fileName = path.Base(req.URL.Path)
+	}
+
+	switch kind {
+	case "image", "video":
+		return uploadStatusMedia(ctx, kind, data, mimetype, caption)
+	case "audio":
+		up, err := cli.Upload(ctx, data, whatsmeow.MediaAudio)
+		if err != nil {
+			return nil, err
+		}
+		return &waProto.Message{AudioMessage: &waProto.AudioMessage{
+			Mimetype:      proto.String(mimetype),
+			URL:           proto.String(up.URL),
+			DirectPath:    proto.String(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    proto.Uint64(up.FileLength),
+		}}, nil
+	default:
+		up, err := cli.Upload(ctx, data, whatsmeow.MediaDocument)
+		if err != nil {
+			return nil, err
+		}
+		return &waProto.Message{DocumentMessage: &waProto.DocumentMessage{
+			Caption:       optString(caption),
+			FileName:      proto.String(fileName),
+			Mimetype:      proto.String(mimetype),
+			URL:           proto.String(up.URL),
+			DirectPath:    proto.String(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    proto.Uint64(up.FileLength),
+		}}, nil
+	}
+}
+
+/* ---------- Rules API ---------- */
+
+func getRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rulesMu.RLock()
+	set := rules
+	rulesMu.RUnlock()
+	if set.Rules == nil {
+		set.Rules = []*rule{}
+	}
+	writeJSON(w, map[string]interface{}{"rules": set.Rules, "file": os.Getenv("RULES_FILE")})
+}
+
+// decodeRules menerima {"rules":[...]} dalam JSON, atau YAML kalau
+// Content-Type berisi "yaml".
+func decodeRules(r *http.Request) (ruleSet, error) {
+	var set ruleSet
+	raw, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return set, err
+	}
+	if strings.Contains(r.Header.Get("Content-Type"), "yaml") {
+		err = yaml.Unmarshal(raw, &set)
+	} else {
+		err = json.Unmarshal(raw, &set)
+	}
+	if err != nil {
+		return set, errors.New("bad rules document: " + err.Error())
+	}
+	return set, compileRules(&set)
+}
+
+func putRulesHandler(w http.ResponseWriter, r *http.Request) {
+	if os.Getenv("RULES_FILE") != "" {
+		writeError(w, 409, "rules are managed by RULES_FILE; edit the file and POST /rules/reload")
+		return
+	}
+	set, err := decodeRules(r)
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	if err := saveSetting(rulesKey, set); err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	rulesMu.Lock()
+	rules = set
+	rulesMu.Unlock()
+	writeJSON(w, map[string]interface{}{"status": "saved", "total": len(set.Rules)})
+}
+
+func reloadRulesHandler(w http.ResponseWriter, r *http.Request) {
+	if err := loadRules(); err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	rulesMu.RLock()
+	total := len(rules.Rules)
+	rulesMu.RUnlock()
+	writeJSON(w, map[string]interface{}{"status": "reloaded", "total": total})
+}
+
+// testRulesHandler (dry-run) mencocokkan contoh pesan dengan rules tanpa
+// mengirim apa pun. Body:
+//
+//	{"rules":[...], "messages":[{"text":"harga?","sender":"0812...","chat_type":"private","time":"2025-01-01T20:00:00+07:00"}]}
+//
+// Kalau "rules" tidak diisi, rules yang sedang aktif yang dipakai.
+func testRulesHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Rules    []*rule `json:"rules"`
+		Messages []struct {
+			Text     string    `json:"text"`
+			Sender   string    `json:"sender"`
+			Chat     string    `json:"chat"`
+			ChatType string    `json:"chat_type"`
+			Type     string    `json:"type"`
+			PushName string    `json:"push_name"`
+			Time     time.Time `json:"time"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, 400, "bad json")
+		return
+	}
+	var set ruleSet
+	if body.Rules != nil {
+		set.Rules = body.Rules
+		if err := compileRules(&set); err != nil {
+			writeError(w, 400, err.Error())
+			return
+		}
+	} else {
+		rulesMu.RLock()
+		set = rules
+		rulesMu.RUnlock()
+	}
+
+	results := make([]map[string]interface{}, 0, len(body.Messages))
+	for i, in := range body.Messages {
+		e := msgEvent{ID: fmt.Sprintf("test-%d", i+1), Text: in.Text, PushName: in.PushName,
+			Type: in.Type, Time: in.Time, IsGroup: in.ChatType == "group"}
+		if e.Type == "" {
+			e.Type = "text"
+		}
+		if e.Time.IsZero() {
+			e.Time = time.Now()
+		}
+		if in.Sender != "" {
+			jid, err := phone.NormalizeJID(in.Sender)
+			if err != nil {
+				writeError(w, 400, fmt.Sprintf("messages[%d].sender: %v", i, err))
+				return
+			}
+			e.Sender = jid.String()
+		}
+		e.Chat = e.Sender
+		if in.Chat != "" {
+			jid, err := phone.NormalizeJID(in.Chat)
+			if err != nil {
+				writeError(w, 400, fmt.Sprintf("messages[%d].chat: %v", i, err))
+				return
+			}
+			e.Chat = jid.String()
+			e.IsGroup = e.IsGroup || jid.Server == types.GroupServer
+		}
+		hits := planRules(set, e)
+		if hits == nil {
+			hits = []ruleHit{}
+		}
+		results = append(results, map[string]interface{}{"message": e, "matched": hits})
+	}
+	writeJSON(w, map[string]interface{}{"results": results})
+}
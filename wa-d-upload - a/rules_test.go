@@ -0,0 +1,150 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockMinutes(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{in: "00:00", want: 0},
+		{in: "08:00", want: 480},
+		{in: "08.30", want: 510},
+		{in: "8:05", want: 485},
+		{in: "23:59", want: 1439},
+		{in: "24:00", want: 1440},
+		{in: "24:30", wantErr: true},
+		{in: "24:59", wantErr: true},
+		{in: "25:00", wantErr: true},
+		{in: "12:60", wantErr: true},
+		{in: "-1:00", wantErr: true},
+		{in: "08", wantErr: true},
+		{in: "jam 8", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := clockMinutes(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("clockMinutes(%q) = %d, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("clockMinutes(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestTimeWindowContains(t *testing.T) {
+	// 2025-10-18 adalah hari Sabtu
+	at := func(clock string) time.Time {
+		ts, err := time.Parse("2006-01-02 15:04", "2025-10-18 "+clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	tests := []struct {
+		name string
+		tw   timeWindow
+		at   string
+		want bool
+	}{
+		{name: "jam kerja, di dalam", tw: timeWindow{From: "08:00", To: "17:00"}, at: "08:00", want: true},
+		{name: "jam kerja, batas akhir", tw: timeWindow{From: "08:00", To: "17:00"}, at: "17:00", want: false},
+		{name: "jam kerja, sebelum", tw: timeWindow{From: "08:00", To: "17:00"}, at: "07:59", want: false},
+		{name: "lewat tengah malam, malam", tw: timeWindow{From: "17:00", To: "08:00"}, at: "23:30", want: true},
+		{name: "lewat tengah malam, dini hari", tw: timeWindow{From: "17:00", To: "08:00"}, at: "00:15", want: true},
+		{name: "lewat tengah malam, siang", tw: timeWindow{From: "17:00", To: "08:00"}, at: "12:00", want: false},
+		{name: "sampai 24:00", tw: timeWindow{From: "22:00", To: "24:00"}, at: "23:59", want: true},
+		{name: "hari cocok", tw: timeWindow{From: "00:00", To: "24:00", Days: []string{"sat", "sun"}}, at: "10:00", want: true},
+		{name: "hari indonesia", tw: timeWindow{From: "00:00", To: "24:00", Days: []string{"Sab"}}, at: "10:00", want: true},
+		{name: "hari tidak cocok", tw: timeWindow{From: "00:00", To: "24:00", Days: []string{"mon", "fri"}}, at: "10:00", want: false},
+		{name: "zona waktu", tw: timeWindow{From: "08:00", To: "17:00", TZ: "Etc/GMT-7"}, at: "02:00", want: true},
+	}
+	for _, tt := range tests {
+		m := ruleMatch{Time: &tt.tw}
+		if err := m.compile(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if tt.tw.TZ == "" {
+			tt.tw.loc = time.UTC
+		}
+		if got := tt.tw.contains(at(tt.at)); got != tt.want {
+			t.Errorf("%s: contains(%s) = %v, want %v", tt.name, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestRuleMatch(t *testing.T) {
+	private := msgEvent{Chat: "628123456789@s.whatsapp.net", Sender: "628123456789@s.whatsapp.net", Phone: "628123456789", Type: "text"}
+	lid := msgEvent{Chat: "123456789012345@lid", Sender: "123456789012345@lid", Phone: "628123456789", Type: "text"}
+	group := msgEvent{Chat: "120363000000000000@g.us", Sender: "628999888777@s.whatsapp.net", Phone: "628999888777", IsGroup: true, Type: "text"}
+	with := func(e msgEvent, text string) msgEvent { e.Text = text; return e }
+
+	tests := []struct {
+		name   string
+		m      ruleMatch
+		e      msgEvent
+		want   bool
+		groups map[string]string
+	}{
+		{name: "tanpa syarat", e: with(private, "apa saja"), want: true},
+		{name: "keyword", m: ruleMatch{Keywords: []string{"harga"}}, e: with(private, "Berapa HARGA paket?"), want: true},
+		{name: "keyword salah satu", m: ruleMatch{Keywords: []string{"promo", "price list"}}, e: with(private, "minta price list"), want: true},
+		{name: "keyword tidak ada", m: ruleMatch{Keywords: []string{"harga"}}, e: with(private, "halo"), want: false},
+		{name: "keyword kosong diabaikan", m: ruleMatch{Keywords: []string{""}}, e: with(private, "halo"), want: false},
+		{
+			name: "regex dengan group", m: ruleMatch{Regex: `(?i)^cek (?P<invoice>\d{6})$`}, e: with(private, "Cek 123456"),
+			want: true, groups: map[string]string{"invoice": "123456"},
+		},
+		{name: "regex tidak cocok", m: ruleMatch{Regex: `^cek \d{6}$`}, e: with(private, "cek 12"), want: false},
+		{name: "keyword dan regex", m: ruleMatch{Keywords: []string{"cek"}, Regex: `\d+`}, e: with(private, "cek saja"), want: false},
+		{name: "chat_type private", m: ruleMatch{ChatType: "private"}, e: with(group, "x"), want: false},
+		{name: "chat_type group", m: ruleMatch{ChatType: "group"}, e: with(group, "x"), want: true},
+		{name: "sender nomor lokal", m: ruleMatch{Senders: []string{"08123456789"}}, e: with(private, "x"), want: true},
+		{name: "sender lewat lid", m: ruleMatch{Senders: []string{"+62 812-3456-789"}}, e: with(lid, "x"), want: true},
+		{name: "sender lain", m: ruleMatch{Senders: []string{"08123456789"}}, e: with(group, "x"), want: false},
+		{name: "chat", m: ruleMatch{Chats: []string{"120363000000000000@g.us"}}, e: with(group, "x"), want: true},
+		{name: "types", m: ruleMatch{Types: []string{"image"}}, e: with(private, "x"), want: false},
+	}
+	for _, tt := range tests {
+		if err := tt.m.compile(); err != nil {
+			t.Fatalf("%s: compile: %v", tt.name, err)
+		}
+		data := newRuleData(tt.e)
+		if got := tt.m.matches(tt.e, &data); got != tt.want {
+			t.Errorf("%s: matches = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for k, v := range tt.groups {
+			if data.Groups[k] != v {
+				t.Errorf("%s: Groups[%q] = %q, want %q", tt.name, k, data.Groups[k], v)
+			}
+		}
+	}
+}
+
+func TestRuleMatchCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		m    ruleMatch
+	}{
+		{name: "regex", m: ruleMatch{Regex: "("}},
+		{name: "chat_type", m: ruleMatch{ChatType: "channel"}},
+		{name: "sender", m: ruleMatch{Senders: []string{"bukan nomor"}}},
+		{name: "jam", m: ruleMatch{Time: &timeWindow{From: "24:30", To: "08:00"}}},
+		{name: "hari", m: ruleMatch{Time: &timeWindow{From: "08:00", To: "17:00", Days: []string{"someday"}}}},
+		{name: "tz", m: ruleMatch{Time: &timeWindow{From: "08:00", To: "17:00", TZ: "Mars/Olympus"}}},
+	}
+	for _, tt := range tests {
+		if err := tt.m.compile(); err == nil {
+			t.Errorf("%s: compile succeeded, want error", tt.name)
+		}
+	}
+}
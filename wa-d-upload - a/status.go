@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+	"wa-common/phone"
+)
+
+/* ---------- Status (stories) ---------- */
+
+// statusLifetime sama dengan umur status di aplikasi WhatsApp; status yang
+// lebih tua dihapus dari gateway.db.
+const statusLifetime = 24 * time.Hour
+
+type statusItem struct {
+	ID       string    `json:"id"`
+	Sender   string    `json:"sender"`
+	PushName string    `json:"push_name,omitempty"`
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	MediaURL string    `json:"media_url,omitempty"` // path gateway untuk download media
+	Time     time.Time `json:"time"`
+}
+
+func registerStatusRoutes() {
+	http.HandleFunc("POST /status", postStatusHandler)
+	http.HandleFunc("GET /status", listStatusHandler)
+	http.HandleFunc("GET /status/privacy", statusPrivacyHandler)
+	http.HandleFunc("GET /status/{id}/media", statusMediaHandler)
+}
+
+// postStatusHandler menerima salah satu:
+//
+//	{"type":"text","text":"Promo hari ini!","background":"#128C7E","font":1}
+//	{"type":"image","media":"<base64>","caption":"..."}
+//	{"type":"video","media":"<base64>","caption":"..."}
+//
+// Penerima status selalu mengikuti pengaturan privasi status default di HP:
+// whatsmeow menyusun daftar penerima sendiri dan tidak bisa diberi daftar
+// per status, jadi field "audience" ditolak. "expect_audience"
+// (contacts|blacklist|whitelist) adalah pengaman: kalau pengaturan di HP
+// berbeda, status tidak dikirim (409). Respons menyebut audience yang
+// dipakai.
+func postStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireLogin(w) {
+		return
+	}
+	var body struct {
+		Type       string           `json:"type"`
+		Text       string           `json:"text"`
+		Caption    string           `json:"caption"`
+		Media      string           `json:"media"`
+		Mimetype   string           `json:"mimetype"`
+		Background string           `json:"background"`
+		Font       int32            `json:"font"`
+		Expect     string           `json:"expect_audience"`
+		Audience   *json.RawMessage `json:"audience"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, 400, "bad json")
+		return
+	}
+	if body.Audience != nil {
+		writeError(w, 400, "per-status audience is not supported; status goes to the phone's default status privacy (see GET /status/privacy), use expect_audience to guard it")
+		return
+	}
+	switch types.StatusPrivacyType(body.Expect) {
+	case "", types.StatusPrivacyTypeContacts, types.StatusPrivacyTypeBlacklist, types.StatusPrivacyTypeWhitelist:
+	default:
+		writeError(w, 400, "expect_audience must be contacts, blacklist or whitelist")
+		return
+	}
+	privacy, err := cli.GetStatusPrivacy()
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	audience, ok := defaultStatusPrivacy(privacy)
+	if !ok {
+		writeError(w, 502, "whatsapp returned no default status privacy setting")
+		return
+	}
+	if body.Expect != "" && string(audience.Type) != body.Expect {
+		writeError(w, 409, "status audience on the phone is "+string(audience.Type)+", not "+body.Expect)
+		return
+	}
+
+	var msg *waProto.Message
+	switch body.Type {
+	case "", "text":
+		if body.Text == "" {
+			writeError(w, 400, "text is required")
+			return
+		}
+		ext := &waProto.ExtendedTextMessage{
+			Text:           proto.String(body.Text),
+			TextArgb:       proto.Uint32(0xFFFFFFFF),
+			BackgroundArgb: proto.Uint32(0xFF128C7E),
+			Font:           waProto.ExtendedTextMessage_FontType(body.Font).Enum(),
+		}
+		if body.Background != "" {
+			argb, err := parseColor(body.Background)
+			if err != nil {
+				writeError(w, 400, err.Error())
+				return
+			}
+			ext.BackgroundArgb = proto.Uint32(argb)
+		}
+		msg = &waProto.Message{ExtendedTextMessage: ext}
+	case "image", "video":
+		data, err := base64.StdEncoding.DecodeString(body.Media)
+		if err != nil || len(data) == 0 {
+			writeError(w, 400, "media must be base64")
+			return
+		}
+		mimetype := body.Mimetype
+		if mimetype == "" {
+			mimetype = http.DetectContentType(data)
+		}
+		if !strings.HasPrefix(mimetype, body.Type+"/") {
+			writeError(w, 400, "media is "+mimetype+", expected "+body.Type)
+			return
+		}
+		if msg, err = uploadStatusMedia(r.Context(), body.Type, data, mimetype, body.Caption); err != nil {
+			writeError(w, 500, err.Error())
+			return
+		}
+	default:
+		writeError(w, 400, "type must be text, image or video")
+		return
+	}
+
+	resp, err := cli.SendMessage(r.Context(), types.StatusBroadcastJID, msg)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]interface{}{"status": "sent", "id": resp.ID, "audience": audience.Type})
+}
+
+// defaultStatusPrivacy mengambil pengaturan yang ditandai default di HP.
+func defaultStatusPrivacy(privacy []types.StatusPrivacy) (types.StatusPrivacy, bool) {
+	for _, p := range privacy {
+		if p.IsDefault {
+			return p, true
+		}
+	}
+	return types.StatusPrivacy{}, false
+}
+
+func uploadStatusMedia(ctx context.Context, kind string, data []byte, mimetype, caption string) (*waProto.Message, error) {
+	mediaType := whatsmeow.MediaImage
+	if kind == "video" {
+		mediaType = whatsmeow.MediaVideo
+	}
+	up, err := cli.Upload(ctx, data, mediaType)
+	if err != nil {
+		return nil, err
+	}
+	if kind == "video" {
+		return &waProto.Message{VideoMessage: &waProto.VideoMessage{
+			Caption:       optString(caption),
+			Mimetype:      proto.String(mimetype),
+			URL:           proto.String(up.URL),
+			DirectPath:    proto.String(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    proto.Uint64(up.FileLength),
+		}}, nil
+	}
+	return &waProto.Message{ImageMessage: &waProto.ImageMessage{
+		Caption:       optString(caption),
+		Mimetype:      proto.String(mimetype),
+		URL:           proto.String(up.URL),
+		DirectPath:    proto.String(up.DirectPath),
+		MediaKey:      up.MediaKey,
+		FileEncSHA256: up.FileEncSHA256,
+		FileSHA256:    up.FileSHA256,
+		FileLength:    proto.Uint64(up.FileLength),
+	}}, nil
+}
+
+// parseColor menerima "#RRGGBB" atau "#AARRGGBB".
+func parseColor(s string) (uint32, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 6 {
+		hex = "FF" + hex
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || err != nil {
+		return 0, errors.New("background must be #RRGGBB or #AARRGGBB")
+	}
+	return uint32(v), nil
+}
+
+// listStatusHandler menampilkan status kontak 24 jam terakhir, bisa difilter ?sender=.
+func listStatusHandler(w http.ResponseWriter, r *http.Request) {
+	query := `SELECT id, sender, push_name, type, text, has_media, posted_at FROM statuses WHERE posted_at >= ?`
+	args := []interface{}{time.Now().Add(-statusLifetime).Unix()}
+	if s := r.URL.Query().Get("sender"); s != "" {
+		jid, err := phone.NormalizeJID(s)
+		if err != nil {
+			writeError(w, 400, err.Error())
+			return
+		}
+		jid, _ = phoneJID(jid, types.EmptyJID)
+		query += ` AND sender = ?`
+		args = append(args, jid.String())
+	}
+	rows, err := gwDB.Query(query+` ORDER BY posted_at DESC`, args...)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	defer rows.Close()
+	out := []statusItem{}
+	for rows.Next() {
+		var it statusItem
+		var hasMedia bool
+		var posted int64
+		if err := rows.Scan(&it.ID, &it.Sender, &it.PushName, &it.Type, &it.Text, &hasMedia, &posted); err != nil {
+			writeError(w, 500, err.Error())
+			return
+		}
+		it.Time = time.Unix(posted, 0)
+		if hasMedia {
+			it.MediaURL = "/status/" + it.ID + "/media"
+		}
+		out = append(out, it)
+	}
+	writeJSON(w, out)
+}
+
+func statusPrivacyHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireLogin(w) {
+		return
+	}
+	privacy, err := cli.GetStatusPrivacy()
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	out := make([]map[string]interface{}, 0, len(privacy))
+	for _, p := range privacy {
+		list := make([]string, 0, len(p.List))
+		for _, jid := range p.List {
+			list = append(list, jid.String())
+		}
+		out = append(out, map[string]interface{}{"type": p.Type, "list": list, "default": p.IsDefault})
+	}
+	writeJSON(w, out)
+}
+
+// statusMediaHandler mendownload dan mendekripsi media status dari server WhatsApp.
+func statusMediaHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireLogin(w) {
+		return
+	}
+	var raw []byte
+	err := gwDB.QueryRow(`SELECT message FROM statuses WHERE id = ? AND has_media = 1`, r.PathValue("id")).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, 404, "status media not found")
+		return
+	} else if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	var msg waProto.Message
+	if err := proto.Unmarshal(raw, &msg); err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	data, err := cli.DownloadAny(r.Context(), &msg)
+	if err != nil {
+		writeError(w, 502, err.Error())
+		return
+	}
+	mimetype := msg.GetImageMessage().GetMimetype()
+	if msg.GetVideoMessage() != nil {
+		mimetype = msg.GetVideoMessage().GetMimetype()
+	} else if msg.GetAudioMessage() != nil {
+		mimetype = msg.GetAudioMessage().GetMimetype()
+	}
+	w.Header().Set("Content-Type", mimetype)
+	_, _ = w.Write(data)
+}
+
+/* ---------- Status events ---------- */
+
+// handleStatusMessage menyimpan status kontak dan mengirim event
+// status.posted / status.deleted. Status tidak ikut diteruskan sebagai pesan
+// biasa. Pengirim @lid disimpan dengan nomornya supaya ?sender= cocok.
+func handleStatusMessage(v *events.Message) {
+	pn, _ := phoneJID(v.Info.Sender, v.Info.SenderAlt)
+	sender := pn.String()
+	if pm := v.Message.GetProtocolMessage(); pm != nil {
+		if pm.GetType() == waProto.ProtocolMessage_REVOKE {
+			id := pm.GetKey().GetID()
+			_, _ = gwDB.Exec(`DELETE FROM statuses WHERE id = ?`, id)
+			emit("status.deleted", v.Info.Timestamp, map[string]string{"id": id, "sender": sender})
+		}
+		return
+	}
+
+	m := v.Message
+	hasMedia := m.GetImageMessage() != nil || m.GetVideoMessage() != nil || m.GetAudioMessage() != nil
+	raw, _ := proto.Marshal(m)
+	item := statusItem{
+		ID:       v.Info.ID,
+		Sender:   sender,
+		PushName: v.Info.PushName,
+		Type:     messageType(m),
+		Text:     messageText(m),
+		Time:     v.Info.Timestamp,
+	}
+	if hasMedia {
+		item.MediaURL = "/status/" + item.ID + "/media"
+	}
+	_, _ = gwDB.Exec(`INSERT OR REPLACE INTO statuses (id, sender, push_name, type, text, has_media, message, posted_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		item.ID, item.Sender, item.PushName, item.Type, item.Text, hasMedia, raw, item.Time.Unix())
+	_, _ = gwDB.Exec(`DELETE FROM statuses WHERE posted_at < ?`, time.Now().Add(-statusLifetime).Unix())
+	emit("status.posted", v.Info.Timestamp, item)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+/* ---------- Typed event stream ---------- */
+
+// gwEvent adalah envelope untuk event bertipe (group.*, picture.*, ...) yang
+// dikirim ke semua webhook dan subscriber WebSocket /wss.
+type gwEvent struct {
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// Tiap subscriber /wss punya antrean kirim sendiri (wsSendBuffer pesan)
+// yang ditulis oleh goroutine writer-nya. broadcast tidak pernah menulis ke
+// socket; kalau antrean klien penuh, klien dianggap lambat dan diputus
+// supaya tidak menahan subscriber lain.
+const (
+	wsSendBuffer   = 64
+	wsWriteTimeout = 10 * time.Second
+)
+
+var (
+	upgrader  = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+	wsClients = make(map[*websocket.Conn]chan []byte)
+	wsMutex   sync.Mutex
+)
+
+func emit(typ string, ts time.Time, data interface{}) {
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	evt := gwEvent{Type: typ, Time: ts, Data: data}
+	go pushWebhook(evt, nil)
+	go broadcast(evt)
+}
+
+func wssHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	send := make(chan []byte, wsSendBuffer)
+	wsMutex.Lock()
+	wsClients[conn] = send
+	wsMutex.Unlock()
+
+	go wsWriter(conn, send)
+	defer func() {
+		dropWSClient(conn)
+		conn.Close()
+	}()
+	// keep conn alive
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			break
+		}
+	}
+}
+
+// wsWriter mengirim isi antrean klien sampai antrean ditutup atau tulis gagal.
+func wsWriter(conn *websocket.Conn, send chan []byte) {
+	for data := range send {
+		_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			dropWSClient(conn)
+			conn.Close()
+			return
+		}
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
+
+// dropWSClient melepas klien dan menutup antreannya; aman dipanggil berulang.
+func dropWSClient(conn *websocket.Conn) {
+	wsMutex.Lock()
+	if send, ok := wsClients[conn]; ok {
+		delete(wsClients, conn)
+		close(send)
+	}
+	wsMutex.Unlock()
+}
+
+func broadcast(msg interface{}) {
+	data, _ := json.Marshal(msg)
+	var slow []*websocket.Conn
+	wsMutex.Lock()
+	for c, send := range wsClients {
+		select {
+		case send <- data:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	wsMutex.Unlock()
+	for _, c := range slow {
+		dropWSClient(c)
+		c.Close()
+	}
+}
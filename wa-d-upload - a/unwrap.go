@@ -0,0 +1,41 @@
+package main
+
+import "go.mau.fi/whatsmeow/types/events"
+
+/* ---------- Message wrappers ---------- */
+
+// msgFlags adalah informasi pembungkus pesan. whatsmeow sudah melepas
+// pembungkusnya (events.Message.UnwrapRaw) sebelum event sampai ke kita;
+// di sini hanya dicatat ulang untuk webhook plus beberapa turunan.
+type msgFlags struct {
+	Ephemeral        bool   `json:"ephemeral,omitempty"`
+	EphemeralSeconds uint32 `json:"ephemeral_seconds,omitempty"`
+	ViewOnce         bool   `json:"view_once,omitempty"`
+	DeviceSent       bool   `json:"device_sent,omitempty"`
+	Destination      string `json:"destination,omitempty"` // chat tujuan untuk pesan device-sent
+	Edited           bool   `json:"edited,omitempty"`
+}
+
+// flagsOf membaca flag pembungkus dari event whatsmeow.
+func flagsOf(v *events.Message) msgFlags {
+	f := msgFlags{
+		Ephemeral: v.IsEphemeral,
+		ViewOnce:  v.IsViewOnce || v.IsViewOnceV2 || v.IsViewOnceV2Extension,
+		Edited:    v.IsEdit,
+	}
+	if meta := v.Info.DeviceSentMeta; meta != nil {
+		f.DeviceSent = true
+		f.Destination = meta.DestinationJID
+	}
+	// durasi pesan sementara ada di ContextInfo isi pesan, bukan di pembungkusnya
+	if exp := contextInfo(v.Message).GetExpiration(); exp > 0 {
+		f.Ephemeral = true
+		f.EphemeralSeconds = exp
+	}
+	// view-once juga bisa ditandai langsung di media tanpa pembungkus
+	m := v.Message
+	if m.GetImageMessage().GetViewOnce() || m.GetVideoMessage().GetViewOnce() || m.GetAudioMessage().GetViewOnce() {
+		f.ViewOnce = true
+	}
+	return f
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+/* ---------- Edits, revokes & reactions ---------- */
+
+// messageUpdate adalah payload untuk message.edit, message.revoke dan
+// message.reaction. TargetID selalu menunjuk ke id pesan asli.
+type messageUpdate struct {
+	ID       string    `json:"id"`
+	TargetID string    `json:"target_id"`
+	Chat     string    `json:"chat"`
+	Sender   string    `json:"sender"`
+	PushName string    `json:"push_name,omitempty"`
+	Time     time.Time `json:"time"`
+
+	Text    string `json:"text,omitempty"`     // message.edit: teks baru
+	Type    string `json:"type,omitempty"`     // message.edit: tipe konten baru
+	ByAdmin bool   `json:"by_admin,omitempty"` // message.revoke: dihapus admin grup
+	Emoji   string `json:"emoji,omitempty"`    // message.reaction
+	Removed bool   `json:"removed,omitempty"`  // message.reaction: reaksi dicabut
+
+	archiveChat, archiveSender string // kunci di arsip, lihat archiveInsert
+}
+
+// handleMessageUpdate mengenali edit, hapus-untuk-semua dan reaksi, lalu
+// mengirimnya sebagai event tersendiri. Mengembalikan true kalau pesan sudah
+// ditangani dan tidak perlu diteruskan sebagai pesan biasa.
+func handleMessageUpdate(v *events.Message) bool {
+	upd := messageUpdate{
+		ID:       v.Info.ID,
+		Chat:     v.Info.Chat.String(),
+		Sender:   v.Info.Sender.ToNonAD().String(),
+		PushName: v.Info.PushName,
+		Time:     v.Info.Timestamp,
+	}
+	sender, _ := phoneJID(v.Info.Sender, v.Info.SenderAlt)
+	upd.archiveChat, upd.archiveSender = chatJID(v.Info.MessageSource).String(), sender.String()
+
+	if r := v.Message.GetReactionMessage(); r != nil {
+		upd.TargetID = r.GetKey().GetID()
+		upd.Emoji = r.GetText()
+		upd.Removed = upd.Emoji == ""
+		archiveUpdate("message.reaction", upd)
+		emit("message.reaction", upd.Time, upd)
+		return true
+	}
+
+	// REVOKE bernilai 0, jadi pastikan ProtocolMessage memang ada dulu
+	pm := v.Message.GetProtocolMessage()
+	switch {
+	case pm != nil && pm.GetType() == waProto.ProtocolMessage_REVOKE:
+		upd.TargetID = pm.GetKey().GetID()
+		upd.ByAdmin = v.Info.Edit == types.EditAttributeAdminRevoke
+		archiveUpdate("message.revoke", upd)
+		emit("message.revoke", upd.Time, upd)
+		return true
+	case pm != nil && pm.GetType() == waProto.ProtocolMessage_MESSAGE_EDIT:
+		edited := pm.GetEditedMessage()
+		upd.TargetID = pm.GetKey().GetID()
+		upd.Text = messageText(edited)
+		upd.Type = messageType(edited)
+		archiveUpdate("message.edit", upd)
+		emit("message.edit", upd.Time, upd)
+		return true
+	case v.IsEdit:
+		// edit format lama: EditedMessage tanpa ProtocolMessage
+		upd.TargetID = v.Info.ID
+		upd.Text = messageText(v.Message)
+		upd.Type = messageType(v.Message)
+		archiveUpdate("message.edit", upd)
+		emit("message.edit", upd.Time, upd)
+		return true
+	}
+	return false
+}
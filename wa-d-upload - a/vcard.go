@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"google.golang.org/protobuf/proto"
+	"wa-common/phone"
+)
+
+/* ---------- Contact cards (vCard) ---------- */
+
+func registerContactCardRoutes() {
+	http.HandleFunc("POST /send/contact", sendContactHandler)
+}
+
+type contactPhone struct {
+	Number string `json:"number"`
+	WAID   string `json:"waid,omitempty"` // nomor WhatsApp tanpa "+", kalau ada
+	Type   string `json:"type,omitempty"` // CELL, WORK, HOME, ...
+}
+
+type contactCard struct {
+	Name   string         `json:"name"`
+	Org    string         `json:"org,omitempty"`
+	Phones []contactPhone `json:"phones,omitempty"`
+	Emails []string       `json:"emails,omitempty"`
+	URL    string         `json:"url,omitempty"`
+	VCard  string         `json:"vcard,omitempty"`
+}
+
+// sendContactHandler menerima {"to":"...","contacts":[{"name":"Budi","phones":[{"number":"0812..."}]}]}.
+// Satu kontak dikirim sebagai ContactMessage, lebih dari satu sebagai ContactsArrayMessage.
+// Field "vcard" boleh diisi langsung kalau sudah punya vCard jadi.
+func sendContactHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireLogin(w) {
+		return
+	}
+	var body struct {
+		To          string        `json:"to"`
+		DisplayName string        `json:"display_name"`
+		Contacts    []contactCard `json:"contacts"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Contacts) == 0 {
+		writeError(w, 400, "bad json")
+		return
+	}
+	jid, err := phone.NormalizeJID(body.To)
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+
+	cards := make([]*waProto.ContactMessage, 0, len(body.Contacts))
+	for i, c := range body.Contacts {
+		if c.Name == "" {
+			writeError(w, 400, fmt.Sprintf("contacts[%d]: name is required", i))
+			return
+		}
+		vcard := c.VCard
+		if vcard == "" {
+			if vcard, err = buildVCard(c); err != nil {
+				writeError(w, 400, fmt.Sprintf("contacts[%d]: %v", i, err))
+				return
+			}
+		}
+		cards = append(cards, &waProto.ContactMessage{DisplayName: proto.String(c.Name), Vcard: proto.String(vcard)})
+	}
+
+	msg := &waProto.Message{ContactMessage: cards[0]}
+	if len(cards) > 1 {
+		name := body.DisplayName
+		if name == "" {
+			name = fmt.Sprintf("%d kontak", len(cards))
+		}
+		msg = &waProto.Message{ContactsArrayMessage: &waProto.ContactsArrayMessage{
+			DisplayName: proto.String(name),
+			Contacts:    cards,
+		}}
+	}
+	resp, err := sendMessage(r.Context(), jid, msg)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"status": "sent", "id": resp.ID})
+}
+
+// buildVCard membuat vCard 3.0 seperti yang dibuat aplikasi WhatsApp, termasuk
+// atribut waid supaya tombol "Kirim pesan" muncul di HP penerima.
+func buildVCard(c contactCard) (string, error) {
+	var b strings.Builder
+	b.WriteString("BEGIN:VCARD\nVERSION:3.0\n")
+	fmt.Fprintf(&b, "N:;%s;;;\n", vcardEscape(c.Name))
+	fmt.Fprintf(&b, "FN:%s\n", vcardEscape(c.Name))
+	if c.Org != "" {
+		fmt.Fprintf(&b, "ORG:%s;\n", vcardEscape(c.Org))
+	}
+	for _, p := range c.Phones {
+		typ := strings.ToUpper(p.Type)
+		if typ == "" {
+			typ = "CELL"
+		}
+		if !vcardParamRe.MatchString(typ) {
+			return "", fmt.Errorf("phone type %q must be letters, digits or '-' only", p.Type)
+		}
+		digits, err := phone.Normalize(p.Number)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "TEL;type=%s;type=VOICE;waid=%s:+%s\n", typ, digits, digits)
+	}
+	for _, e := range c.Emails {
+		fmt.Fprintf(&b, "EMAIL:%s\n", vcardEscape(e))
+	}
+	if c.URL != "" {
+		// URL tidak di-escape (koma dan titik koma sah di URL), jadi yang
+		// bisa memecah baris ditolak
+		if strings.ContainsAny(c.URL, "\r\n") {
+			return "", fmt.Errorf("url must not contain line breaks")
+		}
+		fmt.Fprintf(&b, "URL:%s\n", c.URL)
+	}
+	b.WriteString("END:VCARD")
+	return b.String(), nil
+}
+
+func parseContactMessage(m *waProto.ContactMessage) contactCard {
+	c := parseVCard(m.GetVcard())
+	if c.Name == "" {
+		c.Name = m.GetDisplayName()
+	}
+	c.VCard = m.GetVcard()
+	return c
+}
+
+// parseVCard membaca field yang umum dipakai WhatsApp: FN, ORG, TEL (plus
+// waid), EMAIL dan URL. Baris lanjutan (folding) digabung dulu.
+func parseVCard(raw string) contactCard {
+	var c contactCard
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	raw = strings.ReplaceAll(raw, "\n ", "")
+	for _, line := range strings.Split(raw, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		params := strings.Split(key, ";")
+		name := strings.ToUpper(params[0])
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name = name[i+1:] // item1.TEL -> TEL
+		}
+		switch name {
+		case "FN":
+			c.Name = vcardUnescape(value)
+		case "ORG":
+			c.Org = strings.TrimRight(vcardUnescape(value), ";")
+		case "EMAIL":
+			c.Emails = append(c.Emails, vcardUnescape(value))
+		case "URL":
+			c.URL = value
+		case "TEL":
+			p := contactPhone{Number: value}
+			for _, param := range params[1:] {
+				k, v, _ := strings.Cut(param, "=")
+				switch strings.ToLower(k) {
+				case "waid":
+					p.WAID = v
+				case "type":
+					if p.Type == "" && !strings.EqualFold(v, "VOICE") {
+						p.Type = strings.ToUpper(v)
+					}
+				}
+			}
+			c.Phones = append(c.Phones, p)
+		}
+	}
+	return c
+}
+
+// vcardParamRe membatasi nilai parameter (mis. TEL;type=) supaya tidak bisa
+// menyisipkan parameter atau baris baru.
+var vcardParamRe = regexp.MustCompile(`^[A-Z0-9-]+$`)
+
+var vcardEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\r", `\n`, "\n", `\n`)
+var vcardUnescaper = strings.NewReplacer(`\\`, `\`, `\,`, ",", `\;`, ";", `\n`, "\n", `\N`, "\n")
+
+func vcardEscape(s string) string   { return vcardEscaper.Replace(s) }
+func vcardUnescape(s string) string { return vcardUnescaper.Replace(s) }
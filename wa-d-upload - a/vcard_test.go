@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildVCardRejectsInjection(t *testing.T) {
+	tests := []struct {
+		name    string
+		card    contactCard
+		wantErr bool
+	}{
+		{name: "biasa", card: contactCard{Name: "Budi", Phones: []contactPhone{{Number: "08123456789", Type: "work"}}, URL: "https://a.example/?x=1;y=2"}},
+		{name: "nama multi baris", card: contactCard{Name: "Budi\r\nTEL:+1", Org: "PT A\nURL:x"}},
+		{name: "type dengan baris baru", card: contactCard{Name: "Budi", Phones: []contactPhone{{Number: "08123456789", Type: "CELL\nTEL:+1"}}}, wantErr: true},
+		{name: "type dengan parameter", card: contactCard{Name: "Budi", Phones: []contactPhone{{Number: "08123456789", Type: "CELL;waid=1"}}}, wantErr: true},
+		{name: "type dengan titik dua", card: contactCard{Name: "Budi", Phones: []contactPhone{{Number: "08123456789", Type: "CELL:+1"}}}, wantErr: true},
+		{name: "url multi baris", card: contactCard{Name: "Budi", URL: "https://a.example\nTEL:+1"}, wantErr: true},
+		{name: "url dengan CR", card: contactCard{Name: "Budi", URL: "https://a.example\rTEL:+1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		vcard, err := buildVCard(tt.card)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: buildVCard = %q, want error", tt.name, vcard)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if strings.Contains(vcard, "\r") {
+			t.Errorf("%s: vcard contains CR: %q", tt.name, vcard)
+		}
+		for _, line := range strings.Split(vcard, "\n") {
+			if strings.HasPrefix(line, "TEL:") || strings.HasPrefix(line, "URL:x") {
+				t.Errorf("%s: injected line %q", tt.name, line)
+			}
+		}
+		if got := parseVCard(vcard); got.Name != tt.card.Name && got.Name != strings.ReplaceAll(tt.card.Name, "\r\n", "\n") {
+			t.Errorf("%s: round trip name = %q, want %q", tt.name, got.Name, tt.card.Name)
+		}
+	}
+}
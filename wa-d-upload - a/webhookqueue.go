@@ -0,0 +1,147 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/* ---------- Webhook pause / queue ---------- */
+
+// Selama dijeda, semua payload webhook (pesan dan event) ditahan di memori
+// dan dikirim berurutan saat resume, termasuk aksi lanjutannya (mis.
+// AUTO_READ). Antrean dibatasi WEBHOOK_QUEUE_MAX (default 1000); kalau penuh,
+// payload paling lama dibuang. Antrean hilang kalau gateway restart.
+//
+// Resume mengosongkan antrean di belakang layar (Resuming true, progres di
+// Sent/Failed). Selama itu payload baru tetap antre di belakang supaya
+// urutan terjaga, dan pause di tengah jalan menghentikan pengiriman dengan
+// sisa antrean tetap tersimpan.
+type webhookQueueStatus struct {
+	Paused   bool      `json:"paused"`
+	Resuming bool      `json:"resuming"`
+	PausedAt time.Time `json:"paused_at,omitempty"`
+	Queued   int       `json:"queued"`
+	Dropped  int       `json:"dropped"`
+	Max      int       `json:"max"`
+	Sent     int       `json:"sent"`   // resume terakhir: payload yang diterima webhook
+	Failed   int       `json:"failed"` // resume terakhir: tidak diterima webhook mana pun, tidak dicoba ulang
+}
+
+// queuedWebhook adalah satu payload yang menunggu resume. delivered
+// dijalankan kalau payload diterima minimal satu webhook.
+type queuedWebhook struct {
+	body      []byte
+	delivered func()
+}
+
+var (
+	whQueueMu sync.Mutex
+	whQueue   []queuedWebhook
+	whStatus  = webhookQueueStatus{Max: webhookQueueMax()}
+)
+
+func webhookQueueMax() int {
+	if n, err := strconv.Atoi(os.Getenv("WEBHOOK_QUEUE_MAX")); err == nil && n > 0 {
+		return n
+	}
+	return 1000
+}
+
+func registerWebhookQueueRoutes() {
+	http.HandleFunc("GET /webhook/queue", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, webhookQueue())
+	})
+	http.HandleFunc("POST /webhook/pause", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, pauseWebhooks())
+	})
+	// resume langsung kembali; pantau progresnya lewat GET /webhook/queue
+	http.HandleFunc("POST /webhook/resume", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, resumeWebhooks())
+	})
+}
+
+// enqueueWebhook mengembalikan true kalau body masuk antrean (sedang dijeda).
+func enqueueWebhook(body []byte, delivered func()) bool {
+	whQueueMu.Lock()
+	defer whQueueMu.Unlock()
+	if !whStatus.Paused && !whStatus.Resuming {
+		return false
+	}
+	if len(whQueue) >= whStatus.Max {
+		whQueue = whQueue[1:]
+		whStatus.Dropped++
+	}
+	whQueue = append(whQueue, queuedWebhook{body: body, delivered: delivered})
+	return true
+}
+
+func webhookQueue() webhookQueueStatus {
+	whQueueMu.Lock()
+	defer whQueueMu.Unlock()
+	st := whStatus
+	st.Queued = len(whQueue)
+	return st
+}
+
+// pauseWebhooks juga menghentikan resume yang sedang berjalan; payload yang
+// belum terkirim tetap di antrean.
+func pauseWebhooks() webhookQueueStatus {
+	whQueueMu.Lock()
+	if !whStatus.Paused {
+		whStatus.Paused = true
+		if !whStatus.Resuming {
+			whStatus.PausedAt, whStatus.Dropped = time.Now(), 0
+		}
+	}
+	whQueueMu.Unlock()
+	return webhookQueue()
+}
+
+// resumeWebhooks mencabut jeda dan mulai mengirim isi antrean di goroutine
+// terpisah. Kalau resume sudah berjalan, tidak ada goroutine kedua.
+func resumeWebhooks() webhookQueueStatus {
+	whQueueMu.Lock()
+	if whStatus.Paused {
+		whStatus.Paused = false
+		if !whStatus.Resuming {
+			whStatus.Resuming, whStatus.Sent, whStatus.Failed = true, 0, 0
+			go drainWebhooks()
+		}
+	}
+	whQueueMu.Unlock()
+	return webhookQueue()
+}
+
+// drainWebhooks mengirim antrean satu per satu dari depan dan berhenti
+// kalau antrean kosong (jeda selesai) atau webhook dijeda lagi.
+func drainWebhooks() {
+	for {
+		whQueueMu.Lock()
+		if whStatus.Paused || len(whQueue) == 0 {
+			whStatus.Resuming = false
+			if !whStatus.Paused {
+				whStatus.PausedAt = time.Time{}
+			}
+			whQueueMu.Unlock()
+			return
+		}
+		q := whQueue[0]
+		whQueue = whQueue[1:]
+		whQueueMu.Unlock()
+
+		ok := deliverWebhook(q.body)
+		if ok && q.delivered != nil {
+			q.delivered()
+		}
+		whQueueMu.Lock()
+		if ok {
+			whStatus.Sent++
+		} else {
+			whStatus.Failed++
+		}
+		whQueueMu.Unlock()
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestResumeWebhooksPauseWins memastikan pause di tengah resume tidak hilang
+// dan sisa antrean tetap tersimpan sampai resume berikutnya.
+func TestResumeWebhooksPauseWins(t *testing.T) {
+	got := make(chan string, 10)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf [16]byte
+		n, _ := r.Body.Read(buf[:])
+		got <- string(buf[:n])
+		<-release
+	}))
+	defer srv.Close()
+	addWebhook(srv.URL)
+	defer removeWebhook(srv.URL)
+
+	pauseWebhooks()
+	for _, body := range []string{"a", "b", "c"} {
+		if !enqueueWebhook([]byte(body), nil) {
+			t.Fatalf("enqueueWebhook(%q) while paused = false", body)
+		}
+	}
+	if st := resumeWebhooks(); st.Paused || !st.Resuming {
+		t.Fatalf("after resume: %+v, want resuming", st)
+	}
+	if body := <-got; body != "a" {
+		t.Fatalf("first delivery = %q, want a", body)
+	}
+	// "a" masih dikirim: pause sekarang harus menghentikan pengiriman
+	pauseWebhooks()
+	if !enqueueWebhook([]byte("d"), nil) {
+		t.Fatal("payload during drain was not queued")
+	}
+	release <- struct{}{}
+	st := waitWebhookQueue(t, func(st webhookQueueStatus) bool { return !st.Resuming })
+	if !st.Paused || st.Queued != 3 || st.Sent != 1 {
+		t.Fatalf("after pause during resume: %+v, want paused with 3 queued and 1 sent", st)
+	}
+
+	close(release)
+	resumeWebhooks()
+	for _, want := range []string{"b", "c", "d"} {
+		if body := <-got; body != want {
+			t.Fatalf("delivery = %q, want %q", body, want)
+		}
+	}
+	st = waitWebhookQueue(t, func(st webhookQueueStatus) bool { return !st.Resuming })
+	if st.Paused || st.Queued != 0 || st.Sent != 3 || !st.PausedAt.IsZero() {
+		t.Fatalf("after second resume: %+v, want running with empty queue", st)
+	}
+	if enqueueWebhook([]byte("e"), nil) {
+		t.Fatal("payload queued while webhooks are running")
+	}
+}
+
+func waitWebhookQueue(t *testing.T, done func(webhookQueueStatus) bool) webhookQueueStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st := webhookQueue()
+		if done(st) {
+			return st
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for webhook queue: %+v", st)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}